@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"learn-backend/models"
+	"learn-backend/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const usage = "usage: stats rebuild (--user <id> | --all) [--dry-run]"
+
+// Run runs a maintenance command given on the command line instead of the server
+func Run(db *mongo.Database, args []string) error {
+	if len(args) >= 2 && args[0] == "stats" && args[1] == "rebuild" {
+		return statsRebuild(db, args[2:])
+	}
+	if len(args) >= 2 && args[0] == "users" && args[1] == "set-role" {
+		return usersSetRole(db, args[2:])
+	}
+	return fmt.Errorf("unknown command %q\n%s\n%s", strings.Join(args, " "), usage, usersUsage)
+}
+
+// statsRebuild regenerates card mastery and user statistics from the study sessions,
+// printing each user's changes as it goes
+func statsRebuild(db *mongo.Database, args []string) error {
+	flags := flag.NewFlagSet("stats rebuild", flag.ContinueOnError)
+	userID := flags.String("user", "", "rebuild the statistics of this user")
+	all := flags.Bool("all", false, "rebuild the statistics of all users")
+	dryRun := flags.Bool("dry-run", false, "only print what would change")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if (*userID == "") == !*all {
+		return errors.New(usage)
+	}
+
+	stats := services.NewStatisticsService(db)
+
+	var userIDs []primitive.ObjectID
+	if *all {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		ids, err := stats.StatisticsUserIDs(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+		userIDs = ids
+	} else {
+		id, err := primitive.ObjectIDFromHex(*userID)
+		if err != nil {
+			return fmt.Errorf("invalid user id %q", *userID)
+		}
+		userIDs = []primitive.ObjectID{id}
+	}
+
+	failed := 0
+	for i, id := range userIDs {
+		ctx, cancel := context.WithTimeout(context.Background(), services.StatisticsRebuildTimeout)
+		report, err := stats.RebuildUser(ctx, id, *dryRun)
+		cancel()
+
+		prefix := fmt.Sprintf("[%d/%d] %s", i+1, len(userIDs), id.Hex())
+		if err != nil {
+			failed++
+			fmt.Printf("%s failed: %v\n", prefix, err)
+			continue
+		}
+		printReport(prefix, report)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("rebuilding statistics failed for %d of %d users", failed, len(userIDs))
+	}
+	return nil
+}
+
+func printReport(prefix string, report models.StatisticsRebuildReport) {
+	verb := "updated"
+	if report.DryRun {
+		verb = "would update"
+	}
+	fmt.Printf("%s: %d sessions, %s %d fields, cards +%d ~%d -%d\n",
+		prefix, report.Sessions, verb, len(report.Changes),
+		report.CardsAdded, report.CardsChanged, report.CardsRemoved)
+
+	for _, change := range report.Changes {
+		fmt.Printf("    %s: %v -> %v\n", change.Field, change.Old, change.New)
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"learn-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const usersUsage = "usage: users set-role (--user <id> | --email <email>) --role <student|teacher|admin>"
+
+// usersSetRole sets a user's role. It is the only way to make the first admin,
+// who can then grant the teacher role through the API.
+func usersSetRole(db *mongo.Database, args []string) error {
+	flags := flag.NewFlagSet("users set-role", flag.ContinueOnError)
+	userID := flags.String("user", "", "id of the user")
+	email := flags.String("email", "", "email of the user")
+	role := flags.String("role", "", "new role of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if (*userID == "") == (*email == "") {
+		return errors.New(usersUsage)
+	}
+	switch *role {
+	case models.UserRoleStudent, models.UserRoleTeacher, models.UserRoleAdmin:
+	default:
+		return errors.New(usersUsage)
+	}
+
+	filter := bson.M{"email": *email}
+	if *userID != "" {
+		id, err := primitive.ObjectIDFromHex(*userID)
+		if err != nil {
+			return fmt.Errorf("invalid user id %q", *userID)
+		}
+		filter = bson.M{"_id": id}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := db.Collection("users").FindOneAndUpdate(ctx, filter,
+		bson.M{"$set": bson.M{"role": *role, "updated_at": time.Now()}},
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return errors.New("user not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s (%s): %s -> %s\n", user.ID.Hex(), user.Email, user.Role, *role)
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -14,6 +15,10 @@ type Config struct {
 	JWTExpiry           string
 	RefreshTokenExpiry  string
 	CORSOrigins         []string
+	RevisionRetention   int
+	TrashRetentionDays  int
+	PublicURL           string
+	ReportThreshold     int
 }
 
 func LoadConfig() *Config {
@@ -32,6 +37,10 @@ func LoadConfig() *Config {
 		JWTExpiry:          getEnv("JWT_EXPIRY", "1h"),
 		RefreshTokenExpiry: getEnv("REFRESH_TOKEN_EXPIRY", "720h"),
 		CORSOrigins:        origins,
+		RevisionRetention:  getEnvInt("CARDSET_REVISION_RETENTION", 50),
+		TrashRetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 30),
+		PublicURL:          strings.TrimSuffix(getEnv("PUBLIC_URL", "http://localhost:8080"), "/"),
+		ReportThreshold:    getEnvInt("CARDSET_REPORT_THRESHOLD", 5),
 	}
 }
 
@@ -42,3 +51,11 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"learn-backend/config"
 	"learn-backend/models"
+	"learn-backend/services"
 	"learn-backend/utils"
+	"log"
 	"net/http"
 	"time"
 
@@ -12,15 +14,17 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AuthController struct {
-	db  *mongo.Database
-	cfg *config.Config
+	db    *mongo.Database
+	cfg   *config.Config
+	stats *services.StatisticsService
 }
 
 func NewAuthController(db *mongo.Database, cfg *config.Config) *AuthController {
-	return &AuthController{db: db, cfg: cfg}
+	return &AuthController{db: db, cfg: cfg, stats: services.NewStatisticsService(db)}
 }
 
 func (ac *AuthController) Register(c *gin.Context) {
@@ -79,6 +83,7 @@ func (ac *AuthController) Register(c *gin.Context) {
 		user.ID.Hex(),
 		user.Email,
 		user.Username,
+		user.Role,
 		ac.cfg.JWTSecret,
 		expiry,
 	)
@@ -151,6 +156,7 @@ func (ac *AuthController) Login(c *gin.Context) {
 		user.ID.Hex(),
 		user.Email,
 		user.Username,
+		user.Role,
 		ac.cfg.JWTSecret,
 		expiry,
 	)
@@ -251,6 +257,13 @@ func (ac *AuthController) UpdateProfile(c *gin.Context) {
 	if req.PreferredVoiceID != "" {
 		update["$set"].(bson.M)["preferred_voice_id"] = req.PreferredVoiceID
 	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		update["$set"].(bson.M)["timezone"] = req.Timezone
+	}
 
 	// Update user
 	result := usersCollection.FindOneAndUpdate(
@@ -267,6 +280,13 @@ func (ac *AuthController) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	// Daily stats and streaks are bucketed in the user's timezone; the decoded user is the one before the update
+	if req.Timezone != "" && req.Timezone != user.Timezone {
+		if err := ac.stats.QueueRecalculation(ctx, objID); err != nil {
+			log.Printf("Failed to queue statistics recalculation of user %s: %v", objID.Hex(), err)
+		}
+	}
+
 	// Fetch updated user
 	err = usersCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&user)
 	if err != nil {
@@ -326,6 +346,7 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 		user.ID.Hex(),
 		user.Email,
 		user.Username,
+		user.Role,
 		ac.cfg.JWTSecret,
 		expiry,
 	)
@@ -414,3 +435,38 @@ func (ac *AuthController) LogoutAll(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices successfully"})
 }
+
+// UpdateUserRole sets the role of another user. Admins cannot be changed this way.
+func (ac *AuthController) UpdateUserRole(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	err = ac.db.Collection("users").FindOneAndUpdate(ctx,
+		bson.M{"_id": userObjID, "role": bson.M{"$ne": models.UserRoleAdmin}},
+		bson.M{"$set": bson.M{"role": req.Role, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
@@ -2,9 +2,17 @@ package controllers
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"learn-backend/config"
 	"learn-backend/models"
 	"learn-backend/services"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,11 +25,12 @@ import (
 )
 
 type CardSetController struct {
-	db *mongo.Database
+	db  *mongo.Database
+	cfg *config.Config
 }
 
-func NewCardSetController(db *mongo.Database) *CardSetController {
-	return &CardSetController{db: db}
+func NewCardSetController(db *mongo.Database, cfg *config.Config) *CardSetController {
+	return &CardSetController{db: db, cfg: cfg}
 }
 
 func (csc *CardSetController) GetCardSets(c *gin.Context) {
@@ -32,12 +41,28 @@ func (csc *CardSetController) GetCardSets(c *gin.Context) {
 		return
 	}
 
+	filter := bson.M{"user_id": objID, "deleted_at": nil}
+	// folder_id=root lists the sets outside of all folders
+	if folderID := c.Query("folder_id"); folderID == "root" {
+		filter["folder_id"] = nil
+	} else if folderID != "" {
+		folderObjID, err := primitive.ObjectIDFromHex(folderID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+			return
+		}
+		filter["folder_id"] = folderObjID
+	}
+	if tags := normalizeTags(c.QueryArray("tag")); len(tags) > 0 {
+		filter["tags"] = bson.M{"$all": tags}
+	}
+
 	cardSetsCollection := csc.db.Collection("cardsets")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
-	cursor, err := cardSetsCollection.Find(ctx, bson.M{"user_id": objID}, opts)
+	cursor, err := cardSetsCollection.Find(ctx, filter, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card sets"})
 		return
@@ -57,6 +82,43 @@ func (csc *CardSetController) GetCardSets(c *gin.Context) {
 	c.JSON(http.StatusOK, cardSets)
 }
 
+// GetTags lists the tags of the user's card sets with their usage counts
+func (csc *CardSetController) GetTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := csc.db.Collection("cardsets").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": objID, "deleted_at": nil}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var tags []models.TagCount
+	if err := cursor.All(ctx, &tags); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode tags"})
+		return
+	}
+
+	if tags == nil {
+		tags = []models.TagCount{}
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
 func (csc *CardSetController) GetCardSet(c *gin.Context) {
 	userID := c.GetString("user_id")
 	cardSetID := c.Param("id")
@@ -78,10 +140,10 @@ func (csc *CardSetController) GetCardSet(c *gin.Context) {
 	defer cancel()
 
 	var cardSet models.CardSet
-	err = cardSetsCollection.FindOne(ctx, bson.M{
-		"_id":     cardSetObjID,
-		"user_id": userObjID,
-	}).Decode(&cardSet)
+	err = cardSetsCollection.FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+	}, userObjID, models.CollaboratorRoleViewer)).Decode(&cardSet)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -92,6 +154,7 @@ func (csc *CardSetController) GetCardSet(c *gin.Context) {
 		return
 	}
 
+	setETag(c, cardSet.Version)
 	c.JSON(http.StatusOK, cardSet)
 }
 
@@ -116,12 +179,23 @@ func (csc *CardSetController) CreateCardSet(c *gin.Context) {
 		}
 	}
 
+	cardSetsCollection := csc.db.Collection("cardsets")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	folderID, ok := resolveFolder(ctx, c, csc.db, userObjID, req.FolderID)
+	if !ok {
+		return
+	}
+
 	cardSet := models.CardSet{
 		UserID:      userObjID,
 		Title:       req.Title,
 		Description: req.Description,
 		Language:    req.Language,
 		Cards:       req.Cards,
+		Tags:        normalizeTags(req.Tags),
+		FolderID:    folderID,
 		Progress: models.StudyProgress{
 			TimesStdied: 0,
 			Mastered:    0,
@@ -129,14 +203,11 @@ func (csc *CardSetController) CreateCardSet(c *gin.Context) {
 		IsPublic:       false,
 		DownloadCount:  0,
 		PhoneticStatus: models.PhoneticStatusEmpty,
+		Version:        1,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
 
-	cardSetsCollection := csc.db.Collection("cardsets")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	result, err := cardSetsCollection.InsertOne(ctx, cardSet)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create card set"})
@@ -144,6 +215,7 @@ func (csc *CardSetController) CreateCardSet(c *gin.Context) {
 	}
 
 	cardSet.ID = result.InsertedID.(primitive.ObjectID)
+	setETag(c, cardSet.Version)
 	c.JSON(http.StatusCreated, cardSet)
 }
 
@@ -163,6 +235,11 @@ func (csc *CardSetController) UpdateCardSet(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	var req models.UpdateCardSetRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -191,6 +268,9 @@ func (csc *CardSetController) UpdateCardSet(c *gin.Context) {
 		}
 		update["cards"] = req.Cards
 	}
+	if req.Tags != nil {
+		update["tags"] = normalizeTags(req.Tags)
+	}
 	if req.PhoneticStatus != "" {
 		update["phonetic_status"] = req.PhoneticStatus
 	}
@@ -199,20 +279,28 @@ func (csc *CardSetController) UpdateCardSet(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := cardSetsCollection.UpdateOne(
+	filter := withAccess(bson.M{"_id": cardSetObjID, "deleted_at": nil, "version": versionFilter(version)}, userObjID, models.CollaboratorRoleEditor)
+
+	var previous models.CardSet
+	err = cardSetsCollection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": cardSetObjID, "user_id": userObjID},
-		bson.M{"$set": update},
-	)
+		filter,
+		bson.M{"$set": update, "$inc": bson.M{"version": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
 
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondVersionMismatch(ctx, c, csc.db, cardSetObjID, userObjID, models.CollaboratorRoleEditor)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card set"})
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
-		return
+	// Keep the overwritten content so it can be restored
+	if req.Title != "" || req.Description != "" || req.Language != "" || req.Cards != nil {
+		saveRevision(ctx, csc.db, previous, csc.cfg.RevisionRetention)
 	}
 
 	// Fetch updated card set
@@ -223,6 +311,7 @@ func (csc *CardSetController) UpdateCardSet(c *gin.Context) {
 		return
 	}
 
+	setETag(c, cardSet.Version)
 	c.JSON(http.StatusOK, cardSet)
 }
 
@@ -242,13 +331,30 @@ func (csc *CardSetController) DeleteCardSet(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	cardSetsCollection := csc.db.Collection("cardsets")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := cardSetsCollection.DeleteOne(ctx, bson.M{
-		"_id":     cardSetObjID,
-		"user_id": userObjID,
+	// Only move the set to the trash; the purger removes it for good after the retention period.
+	// A trashed set leaves the library; was_public brings it back there on restore.
+	now := time.Now()
+	result, err := cardSetsCollection.UpdateOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+		"version":    versionFilter(version),
+	}, userObjID, models.CollaboratorRoleAdmin), mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"was_public": "$is_public",
+			"is_public":  false,
+			"deleted_at": now,
+			"updated_at": now,
+			"version":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+		}}},
 	})
 
 	if err != nil {
@@ -256,15 +362,55 @@ func (csc *CardSetController) DeleteCardSet(c *gin.Context) {
 		return
 	}
 
-	if result.DeletedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+	if result.MatchedCount == 0 {
+		respondVersionMismatch(ctx, c, csc.db, cardSetObjID, userObjID, models.CollaboratorRoleAdmin)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Card set deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Card set moved to trash",
+		"purge_after": now.AddDate(0, 0, csc.cfg.TrashRetentionDays),
+	})
 }
 
-func (csc *CardSetController) TogglePublish(c *gin.Context) {
+// GetTrash lists the user's deleted card sets, most recently deleted first
+func (csc *CardSetController) GetTrash(c *gin.Context) {
+	userID := c.GetString("user_id")
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}})
+	cursor, err := csc.db.Collection("cardsets").Find(ctx, bson.M{
+		"user_id":    objID,
+		"deleted_at": bson.M{"$ne": nil},
+	}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var cardSets []models.CardSet
+	if err := cursor.All(ctx, &cardSets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode card sets"})
+		return
+	}
+
+	if cardSets == nil {
+		cardSets = []models.CardSet{}
+	}
+
+	c.JSON(http.StatusOK, cardSets)
+}
+
+// RestoreCardSet moves a card set out of the trash
+func (csc *CardSetController) RestoreCardSet(c *gin.Context) {
 	userID := c.GetString("user_id")
 	cardSetID := c.Param("id")
 
@@ -280,79 +426,321 @@ func (csc *CardSetController) TogglePublish(c *gin.Context) {
 		return
 	}
 
-	cardSetsCollection := csc.db.Collection("cardsets")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Get current card set
-	var cardSet models.CardSet
-	err = cardSetsCollection.FindOne(ctx, bson.M{
-		"_id":     cardSetObjID,
-		"user_id": userObjID,
-	}).Decode(&cardSet)
-
+	cardSet, err := restoreCardSet(ctx, csc.db, bson.M{"_id": cardSetObjID, "user_id": userObjID})
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found in trash"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore card set"})
 		return
 	}
 
-	// Toggle is_public
-	newPublicStatus := !cardSet.IsPublic
+	setETag(c, cardSet.Version)
+	c.JSON(http.StatusOK, cardSet)
+}
 
-	update := bson.M{
-		"is_public":  newPublicStatus,
-		"updated_at": time.Now(),
-	}
+// restoreCardSet moves the trashed card set matching filter out of the trash, republishing it
+// if it was public. Sets the purger already claimed are not matched.
+func restoreCardSet(ctx context.Context, db *mongo.Database, filter bson.M) (models.CardSet, error) {
+	filter["deleted_at"] = bson.M{"$ne": nil}
+	filter["purging_at"] = nil
 
-	_, err = cardSetsCollection.UpdateOne(
-		ctx,
-		bson.M{"_id": cardSetObjID, "user_id": userObjID},
-		bson.M{"$set": update},
-	)
+	var cardSet models.CardSet
+	err := db.Collection("cardsets").FindOneAndUpdate(ctx, filter, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"is_public":  bson.M{"$ifNull": bson.A{"$was_public", false}},
+			"updated_at": time.Now(),
+			"version":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+		}}},
+		{{Key: "$unset", Value: bson.A{"deleted_at", "was_public"}}},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&cardSet)
+	return cardSet, err
+}
+
+func (csc *CardSetController) TogglePublish(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
 
+	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card set"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Fetch updated card set
-	err = cardSetsCollection.FindOne(ctx, bson.M{"_id": cardSetObjID}).Decode(&cardSet)
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated card set"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	cardSetsCollection := csc.db.Collection("cardsets")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Toggle is_public in a single update so the toggle applies to the version the client saw
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"is_public":  bson.M{"$not": bson.A{"$is_public"}},
+			"version":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+			"updated_at": time.Now(),
+		}}},
+	}
+
+	var cardSet models.CardSet
+	err = cardSetsCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": cardSetObjID, "user_id": userObjID, "deleted_at": nil, "version": versionFilter(version)},
+		pipeline,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&cardSet)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondVersionMismatch(ctx, c, csc.db, cardSetObjID, userObjID, "")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card set"})
+		return
+	}
+
+	setETag(c, cardSet.Version)
 	c.JSON(http.StatusOK, cardSet)
 }
 
+// GetGlobalCardSets lists public card sets with search, filters and cursor pagination
 func (csc *CardSetController) GetGlobalCardSets(c *gin.Context) {
-	cardSetsCollection := csc.db.Collection("cardsets")
+	sort := c.DefaultQuery("sort", models.LibrarySortPopular)
+	if sort != models.LibrarySortPopular && sort != models.LibrarySortNewest && sort != models.LibrarySortTrending && sort != models.LibrarySortRating {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be popular, newest, trending or rating"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	minCards, err := strconv.Atoi(c.DefaultQuery("min_cards", "0"))
+	if err != nil || minCards < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_cards must be a non-negative number"})
+		return
+	}
+
+	now := time.Now()
+	var after *libraryCursor
+	if raw := c.Query("cursor"); raw != "" {
+		after, err = decodeLibraryCursor(raw, sort)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		// Trending scores decay over time, so later pages are scored as of the first page
+		now = time.UnixMilli(after.Now)
+	}
+
+	filter := bson.M{"is_public": true, "deleted_at": nil, "hidden": bson.M{"$ne": true}}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		filter["$text"] = bson.M{"$search": q}
+	}
+	if language := c.Query("language"); language != "" {
+		filter["language"] = language
+	}
+	if tags := normalizeTags(c.QueryArray("tag")); len(tags) > 0 {
+		filter["tags"] = bson.M{"$all": tags}
+	}
+	if minCards > 0 {
+		// The set has at least min_cards cards when that array position exists
+		filter["cards."+strconv.Itoa(minCards-1)] = bson.M{"$exists": true}
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+
+	sortField, sortValue := "download_count", interface{}("$download_count")
+	switch sort {
+	case models.LibrarySortNewest:
+		sortField, sortValue = "created_at", bson.M{"$toLong": "$created_at"}
+	case models.LibrarySortTrending:
+		// Downloads per age, decaying like a hot ranking: downloads / (age in hours + 2)^1.5
+		sortField, sortValue = "trending_score", "$trending_score"
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{
+			"trending_score": bson.M{"$divide": bson.A{
+				"$download_count",
+				bson.M{"$pow": bson.A{
+					bson.M{"$add": bson.A{bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, "$created_at"}}, 3600000}}, 2}},
+					1.5,
+				}},
+			}},
+		}}})
+	case models.LibrarySortRating:
+		// Unrated sets have no rating_average and sort last
+		sortField, sortValue = "rating_score", "$rating_score"
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{
+			"rating_score": bson.M{"$ifNull": bson.A{"$rating_average", 0}},
+		}}})
+	}
+
+	if after != nil {
+		var value interface{} = after.Value
+		if sort == models.LibrarySortNewest {
+			value = time.UnixMilli(int64(after.Value))
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{sortField: bson.M{"$lt": value}},
+			bson.M{sortField: value, "_id": bson.M{"$lt": after.ID}},
+		}}}})
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: sortField, Value: -1}, {Key: "_id", Value: -1}}}},
+		bson.D{{Key: "$limit", Value: limit + 1}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"user_id":         1,
+			"title":           1,
+			"description":     1,
+			"language":        1,
+			"tags":            1,
+			"download_count":  1,
+			"rating_average":  1,
+			"rating_count":    1,
+			"phonetic_status": 1,
+			"created_at":      1,
+			"updated_at":      1,
+			"card_count":      bson.M{"$size": bson.M{"$ifNull": bson.A{"$cards", bson.A{}}}},
+			"sort_value":      sortValue,
+		}}},
+	)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	opts := options.Find().SetSort(bson.D{{Key: "download_count", Value: -1}, {Key: "created_at", Value: -1}})
-	cursor, err := cardSetsCollection.Find(ctx, bson.M{"is_public": true}, opts)
+	cursor, err := csc.db.Collection("cardsets").Aggregate(ctx, pipeline)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch global card sets"})
 		return
 	}
 	defer cursor.Close(ctx)
 
-	var cardSets []models.CardSet
-	if err := cursor.All(ctx, &cardSets); err != nil {
+	var items []models.CardSetSummary
+	if err := cursor.All(ctx, &items); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode card sets"})
 		return
 	}
 
-	if cardSets == nil {
-		cardSets = []models.CardSet{}
+	page := models.CardSetPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeLibraryCursor(libraryCursor{
+			Sort:  sort,
+			Value: last.SortValue,
+			ID:    last.ID,
+			Now:   now.UnixMilli(),
+		})
+	}
+	if page.Items == nil {
+		page.Items = []models.CardSetSummary{}
 	}
 
-	c.JSON(http.StatusOK, cardSets)
+	c.JSON(http.StatusOK, page)
+}
+
+// Card preview sizes of GetPublicCardSet
+const (
+	defaultPreviewCards   = 20
+	maxPreviewCards       = 200
+	maxPublicPreviewCards = 20 // without authentication
+)
+
+// GetPublicCardSet returns a public card set with its owner's profile and the first cards.
+// It is also served without authentication for share landing pages, with a smaller preview.
+func (csc *CardSetController) GetPublicCardSet(c *gin.Context) {
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	maxPreview := maxPreviewCards
+	if c.GetString("user_id") == "" {
+		maxPreview = maxPublicPreviewCards
+	}
+	preview, err := strconv.Atoi(c.DefaultQuery("preview", strconv.Itoa(defaultPreviewCards)))
+	if err != nil || preview < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "preview must be a non-negative number"})
+		return
+	}
+	if preview > maxPreview {
+		preview = maxPreview
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := csc.db.Collection("cardsets").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": cardSetObjID, "is_public": true, "deleted_at": nil, "hidden": bson.M{"$ne": true}}}},
+		{{Key: "$project", Value: bson.M{
+			"user_id":         1,
+			"title":           1,
+			"description":     1,
+			"language":        1,
+			"tags":            1,
+			"download_count":  1,
+			"rating_average":  1,
+			"rating_count":    1,
+			"phonetic_status": 1,
+			"created_at":      1,
+			"updated_at":      1,
+			"card_count":      bson.M{"$size": bson.M{"$ifNull": bson.A{"$cards", bson.A{}}}},
+			"cards":           bson.M{"$slice": bson.A{bson.M{"$ifNull": bson.A{"$cards", bson.A{}}}, preview}},
+		}}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if cursor.Err() != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found or not public"})
+		return
+	}
+
+	var detail models.PublicCardSetDetail
+	if err := cursor.Decode(&detail); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode card set"})
+		return
+	}
+
+	// A deleted owner account leaves the profile empty rather than hiding the set
+	err = csc.db.Collection("users").FindOne(ctx,
+		bson.M{"_id": detail.UserID},
+		options.FindOne().SetProjection(bson.M{"username": 1, "full_name": 1, "avatar": 1}),
+	).Decode(&detail.Owner)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set owner"})
+		return
+	}
+	detail.Owner.ID = detail.UserID
+
+	if detail.Cards == nil {
+		detail.Cards = []models.CardSetCard{}
+	}
+
+	c.JSON(http.StatusOK, detail)
 }
 
 func (csc *CardSetController) ImportFromGlobal(c *gin.Context) {
@@ -378,8 +766,10 @@ func (csc *CardSetController) ImportFromGlobal(c *gin.Context) {
 	// Get the public card set
 	var sourceCardSet models.CardSet
 	err = cardSetsCollection.FindOne(ctx, bson.M{
-		"_id":       cardSetObjID,
-		"is_public": true,
+		"_id":        cardSetObjID,
+		"is_public":  true,
+		"deleted_at": nil,
+		"hidden":     bson.M{"$ne": true},
 	}).Decode(&sourceCardSet)
 
 	if err != nil {
@@ -391,36 +781,12 @@ func (csc *CardSetController) ImportFromGlobal(c *gin.Context) {
 		return
 	}
 
-	// Generate new IDs for cards in the imported set
-	for i := range sourceCardSet.Cards {
-		sourceCardSet.Cards[i].ID = uuid.New().String()
-	}
-
-	// Create new card set for the user
-	newCardSet := models.CardSet{
-		UserID:      userObjID,
-		Title:       sourceCardSet.Title,
-		Description: sourceCardSet.Description,
-		Language:    sourceCardSet.Language,
-		Cards:       sourceCardSet.Cards,
-		Progress: models.StudyProgress{
-			TimesStdied: 0,
-			Mastered:    0,
-		},
-		IsPublic:      false,
-		DownloadCount: 0,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-
-	result, err := cardSetsCollection.InsertOne(ctx, newCardSet)
+	newCardSet, err := copyCardSet(ctx, csc.db, sourceCardSet, userObjID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import card set"})
 		return
 	}
 
-	newCardSet.ID = result.InsertedID.(primitive.ObjectID)
-
 	// Increment download count of the source card set
 	_, err = cardSetsCollection.UpdateOne(
 		ctx,
@@ -436,6 +802,57 @@ func (csc *CardSetController) ImportFromGlobal(c *gin.Context) {
 	c.JSON(http.StatusCreated, newCardSet)
 }
 
+// copyCardSet stores a private copy of a card set for the user
+func copyCardSet(ctx context.Context, db *mongo.Database, source models.CardSet, userID primitive.ObjectID) (models.CardSet, error) {
+	newCardSet := newCardSetCopy(source, userID)
+	result, err := db.Collection("cardsets").InsertOne(ctx, newCardSet)
+	if err != nil {
+		return newCardSet, err
+	}
+
+	newCardSet.ID = result.InsertedID.(primitive.ObjectID)
+	return newCardSet, nil
+}
+
+// newCardSetCopy builds a private copy of source for the user, with fresh card IDs mapped to the source cards
+func newCardSetCopy(source models.CardSet, userID primitive.ObjectID) models.CardSet {
+	cards := make([]models.CardSetCard, len(source.Cards))
+	for i, card := range source.Cards {
+		card.ID = uuid.New().String()
+		cards[i] = card
+	}
+
+	newCardSet := models.CardSet{
+		UserID:      userID,
+		Title:       source.Title,
+		Description: source.Description,
+		Language:    source.Language,
+		Cards:       cards,
+		Tags:        source.Tags,
+		Progress: models.StudyProgress{
+			TimesStdied: 0,
+			Mastered:    0,
+		},
+		IsPublic:      false,
+		DownloadCount: 0,
+		Version:       1,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	// Copies of private sets, e.g. through a share link, have no upstream the user could sync from
+	if tracksUpstream(source, userID) {
+		newCardSet.ForkedFrom = services.NewForkOrigin(source, cards)
+	}
+	return newCardSet
+}
+
+// tracksUpstream reports whether the user may follow source as the upstream of a copy,
+// by the same rule findFork looks the upstream up with
+func tracksUpstream(source models.CardSet, userID primitive.ObjectID) bool {
+	return source.UserID == userID || (source.IsPublic && !source.Hidden)
+}
+
 func (csc *CardSetController) GeneratePhonetics(c *gin.Context) {
 	userID := c.GetString("user_id")
 	cardSetID := c.Param("id")
@@ -458,10 +875,10 @@ func (csc *CardSetController) GeneratePhonetics(c *gin.Context) {
 
 	// Get the card set
 	var cardSet models.CardSet
-	err = cardSetsCollection.FindOne(ctx, bson.M{
-		"_id":     cardSetObjID,
-		"user_id": userObjID,
-	}).Decode(&cardSet)
+	err = cardSetsCollection.FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+	}, userObjID, models.CollaboratorRoleEditor)).Decode(&cardSet)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -491,7 +908,7 @@ func (csc *CardSetController) GeneratePhonetics(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
 		return
 	}
-	cardSet.PhoneticStatus = models.PhoneticStatusProcessing
+	originals := append([]models.CardSetCard(nil), cardSet.Cards...)
 
 	// Create phonetic service
 	phoneticService := services.NewPhoneticService()
@@ -531,16 +948,40 @@ func (csc *CardSetController) GeneratePhonetics(c *gin.Context) {
 		finalStatus = models.PhoneticStatusFailed
 	}
 
-	// Update the card set in database
-	update := bson.M{
-		"$set": bson.M{
-			"cards":           cardSet.Cards,
-			"phonetic_status": finalStatus,
-			"updated_at":      time.Now(),
-		},
+	// Write only the generated fields, by card ID and only where they are still empty,
+	// so cards edited, added or removed during the generation are kept as they are
+	set := bson.M{
+		"phonetic_status": finalStatus,
+		"updated_at":      time.Now(),
+	}
+	var arrayFilters []interface{}
+	for i, card := range cardSet.Cards {
+		original := originals[i]
+		if card.Phonetic != original.Phonetic {
+			identifier := fmt.Sprintf("p%d", i)
+			set["cards.$["+identifier+"].phonetic"] = card.Phonetic
+			arrayFilters = append(arrayFilters, bson.M{
+				identifier + ".id":       card.ID,
+				identifier + ".phonetic": bson.M{"$in": bson.A{nil, ""}},
+			})
+		}
+		if card.PartOfSpeech != original.PartOfSpeech {
+			identifier := fmt.Sprintf("s%d", i)
+			set["cards.$["+identifier+"].part_of_speech"] = card.PartOfSpeech
+			arrayFilters = append(arrayFilters, bson.M{
+				identifier + ".id":             card.ID,
+				identifier + ".part_of_speech": bson.M{"$in": bson.A{nil, ""}},
+			})
+		}
+	}
+	update := bson.M{"$set": set}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if len(arrayFilters) > 0 {
+		update["$inc"] = bson.M{"version": 1}
+		opts.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
 	}
 
-	_, err = cardSetsCollection.UpdateOne(ctx, bson.M{"_id": cardSetObjID}, update)
+	err = cardSetsCollection.FindOneAndUpdate(ctx, bson.M{"_id": cardSetObjID, "deleted_at": nil}, update, opts).Decode(&cardSet)
 	if err != nil {
 		// Even if update fails, set status to failed
 		cardSetsCollection.UpdateOne(ctx, bson.M{"_id": cardSetObjID}, bson.M{
@@ -553,6 +994,413 @@ func (csc *CardSetController) GeneratePhonetics(c *gin.Context) {
 		return
 	}
 
-	cardSet.PhoneticStatus = finalStatus
+	setETag(c, cardSet.Version)
+	c.JSON(http.StatusOK, cardSet)
+}
+
+func (csc *CardSetController) AddCards(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.AddCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Added cards always get fresh IDs so they can't collide with existing ones
+	for i := range req.Cards {
+		req.Cards[i].ID = uuid.New().String()
+	}
+
+	push := bson.M{"$each": req.Cards}
+	if req.Position != nil {
+		push["$position"] = *req.Position
+	}
+
+	cardSetsCollection := csc.db.Collection("cardsets")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var previous models.CardSet
+	err = cardSetsCollection.FindOneAndUpdate(
+		ctx,
+		withAccess(bson.M{"_id": cardSetObjID, "deleted_at": nil}, userObjID, models.CollaboratorRoleEditor),
+		bson.M{
+			"$push": bson.M{"cards": push},
+			"$set":  bson.M{"updated_at": time.Now()},
+			"$inc":  bson.M{"version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add cards"})
+		return
+	}
+
+	saveRevision(ctx, csc.db, previous, csc.cfg.RevisionRetention)
+
+	setETag(c, previous.Version+1)
+	c.JSON(http.StatusCreated, gin.H{"cards": req.Cards, "version": previous.Version + 1})
+}
+
+func (csc *CardSetController) UpdateCard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+	cardID := c.Param("cardId")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.UpdateCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := bson.M{
+		"updated_at": time.Now(),
+	}
+
+	fields := map[string]*string{
+		"terminology":    req.Terminology,
+		"define":         req.Define,
+		"example":        req.Example,
+		"image_url":      req.ImageURL,
+		"part_of_speech": req.PartOfSpeech,
+		"phonetic":       req.Phonetic,
+	}
+	for field, value := range fields {
+		if value != nil {
+			update["cards.$[card]."+field] = *value
+		}
+	}
+
+	cardSetsCollection := csc.db.Collection("cardsets")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().
+		SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"card.id": cardID}}}).
+		SetReturnDocument(options.Before)
+
+	var previous models.CardSet
+	err = cardSetsCollection.FindOneAndUpdate(
+		ctx,
+		withAccess(bson.M{"_id": cardSetObjID, "deleted_at": nil, "cards.id": cardID}, userObjID, models.CollaboratorRoleEditor),
+		bson.M{"$set": update, "$inc": bson.M{"version": 1}},
+		opts,
+	).Decode(&previous)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card"})
+		return
+	}
+
+	saveRevision(ctx, csc.db, previous, csc.cfg.RevisionRetention)
+
+	// Fetch updated card set
+	var cardSet models.CardSet
+	err = cardSetsCollection.FindOne(ctx, bson.M{"_id": cardSetObjID}).Decode(&cardSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated card"})
+		return
+	}
+
+	for _, card := range cardSet.Cards {
+		if card.ID == cardID {
+			setETag(c, cardSet.Version)
+			c.JSON(http.StatusOK, models.CardResponse{CardSetCard: card, Version: cardSet.Version})
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+}
+
+func (csc *CardSetController) DeleteCard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+	cardID := c.Param("cardId")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	cardSetsCollection := csc.db.Collection("cardsets")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var previous models.CardSet
+	err = cardSetsCollection.FindOneAndUpdate(
+		ctx,
+		withAccess(bson.M{"_id": cardSetObjID, "deleted_at": nil, "cards.id": cardID}, userObjID, models.CollaboratorRoleEditor),
+		bson.M{
+			"$pull": bson.M{"cards": bson.M{"id": cardID}},
+			"$set":  bson.M{"updated_at": time.Now()},
+			"$inc":  bson.M{"version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete card"})
+		return
+	}
+
+	saveRevision(ctx, csc.db, previous, csc.cfg.RevisionRetention)
+
+	// Drop the mastery records of the removed card; leftovers only skew statistics, so the delete stands
+	if _, err := csc.db.Collection("card_mastery").DeleteMany(ctx, bson.M{
+		"cardset_id": cardSetObjID,
+		"card_id":    cardID,
+	}); err != nil {
+		log.Printf("Failed to delete card mastery of card %s in card set %s: %v", cardID, cardSetObjID.Hex(), err)
+	}
+
+	setETag(c, previous.Version+1)
+	c.JSON(http.StatusOK, gin.H{"message": "Card deleted successfully", "version": previous.Version + 1})
+}
+
+func (csc *CardSetController) ReorderCards(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.ReorderCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, id := range req.CardIDs {
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate card ID: " + id})
+			return
+		}
+		seen[id] = true
+	}
+
+	cardSetsCollection := csc.db.Collection("cardsets")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The filter only matches when card_ids is exactly a permutation of the current cards,
+	// and the pipeline rebuilds the array server-side so concurrent card edits are kept
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"cards": bson.M{"$map": bson.M{
+				"input": req.CardIDs,
+				"as":    "cardId",
+				"in": bson.M{"$arrayElemAt": bson.A{
+					bson.M{"$filter": bson.M{
+						"input": "$cards",
+						"as":    "card",
+						"cond":  bson.M{"$eq": bson.A{"$$card.id", "$$cardId"}},
+					}},
+					0,
+				}},
+			}},
+			"version":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+			"updated_at": time.Now(),
+		}}},
+	}
+
+	var previous models.CardSet
+	err = cardSetsCollection.FindOneAndUpdate(
+		ctx,
+		withAccess(bson.M{
+			"_id":        cardSetObjID,
+			"deleted_at": nil,
+			"cards":      bson.M{"$size": len(req.CardIDs)},
+			"cards.id":   bson.M{"$all": req.CardIDs},
+		}, userObjID, models.CollaboratorRoleEditor),
+		pipeline,
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			// Tell apart a missing set from a card_ids list that doesn't match the set
+			count, countErr := cardSetsCollection.CountDocuments(ctx, withAccess(bson.M{"_id": cardSetObjID, "deleted_at": nil}, userObjID, models.CollaboratorRoleEditor))
+			if countErr == nil && count > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "card_ids must list every card of the set exactly once"})
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder cards"})
+		return
+	}
+
+	saveRevision(ctx, csc.db, previous, csc.cfg.RevisionRetention)
+
+	// Fetch updated card set
+	var cardSet models.CardSet
+	err = cardSetsCollection.FindOne(ctx, bson.M{"_id": cardSetObjID}).Decode(&cardSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated card set"})
+		return
+	}
+
+	setETag(c, cardSet.Version)
 	c.JSON(http.StatusOK, cardSet)
 }
+
+// normalizeTags lowercases and trims tags, dropping empty ones and duplicates
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// setETag exposes the card set version as a strong ETag
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatchVersion reads the card set version from the If-Match header, writing the error response on failure
+func ifMatchVersion(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header required"})
+		return 0, false
+	}
+
+	header = strings.TrimPrefix(header, "W/")
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+		return 0, false
+	}
+
+	return version, true
+}
+
+// versionFilter matches a card set version. Sets created before versioning have no version field.
+func versionFilter(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// respondVersionMismatch answers a write whose filter didn't match: 404 when the set doesn't exist,
+// 403 when the user lacks role on it (empty for owner-only writes), 412 with the current document otherwise
+func respondVersionMismatch(ctx context.Context, c *gin.Context, db *mongo.Database, cardSetID, userID primitive.ObjectID, role string) {
+	var current models.CardSet
+	err := db.Collection("cardsets").FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetID,
+		"deleted_at": nil,
+	}, userID, models.CollaboratorRoleViewer)).Decode(&current)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+
+	if !hasAccess(current, userID, role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change this card set"})
+		return
+	}
+
+	setETag(c, current.Version)
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "Card set has been modified, reload and try again",
+		"current": current,
+	})
+}
+
+// libraryCursor is the position after the last item of a global library page
+type libraryCursor struct {
+	Sort  string             `json:"s"`
+	Value float64            `json:"v"`
+	ID    primitive.ObjectID `json:"id"`
+	Now   int64              `json:"t"`
+}
+
+func encodeLibraryCursor(cursor libraryCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeLibraryCursor(raw, sort string) (*libraryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor libraryCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Sort != sort {
+		return nil, errors.New("cursor belongs to a different sort")
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,319 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"learn-backend/config"
+	"learn-backend/models"
+	"learn-backend/services"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxImportFileSize caps uploaded CSV/TSV import files
+const maxImportFileSize = 5 << 20
+
+// maxAnkiPackageSize caps uploaded Anki packages, which may carry media
+const maxAnkiPackageSize = 50 << 20
+
+// maxImportCards matches the card limit of AddCardsRequest
+const maxImportCards = 1000
+
+type CardSetImportController struct {
+	db            *mongo.Database
+	importService *services.CardImportService
+	ankiService   *services.AnkiService
+	mediaService  *services.MediaService
+}
+
+func NewCardSetImportController(db *mongo.Database, cfg *config.Config) *CardSetImportController {
+	return &CardSetImportController{
+		db:            db,
+		importService: services.NewCardImportService(),
+		ankiService:   services.NewAnkiService(),
+		mediaService:  services.NewMediaService(db, cfg.PublicURL),
+	}
+}
+
+// ImportCardSet creates a card set from an uploaded CSV/TSV file, pasted text or an Anki package.
+// Invalid rows are skipped and reported individually.
+func (ic *CardSetImportController) ImportCardSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// The body limit has to be picked before the form is read, so only uploads declaring
+	// ?format=apkg get the Anki package limit; everything else gets the CSV/TSV one
+	bodyLimit := int64(maxImportFileSize + 1<<20)
+	if c.Query("format") == services.ExportFormatAPKG {
+		bodyLimit = maxAnkiPackageSize + 1<<20
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, bodyLimit)
+
+	var req models.ImportCardSetRequest
+	if err := c.ShouldBind(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import file is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Format == "" && c.Query("format") == services.ExportFormatAPKG {
+		req.Format = services.ExportFormatAPKG
+	}
+
+	fileHeader, fileErr := c.FormFile("file")
+	if req.Format == "" && fileErr == nil {
+		req.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var cards []models.CardSetCard
+	var rowErrors []models.ImportRowError
+
+	if req.Format == services.ExportFormatAPKG {
+		if fileErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An Anki package file is required"})
+			return
+		}
+		if fileHeader.Size > maxAnkiPackageSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import file is too large"})
+			return
+		}
+
+		mapping, err := services.ParseFieldMapping(req.FieldMapping)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+			return
+		}
+		defer file.Close()
+
+		pkg, err := ic.ankiService.ReadPackage(file, fileHeader.Size)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		cards, rowErrors = ic.importAnkiCards(ctx, userObjID, pkg, mapping)
+	} else {
+		columns, err := services.ParseColumns(req.Columns)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		text := req.Text
+		if fileErr == nil {
+			if fileHeader.Size > maxImportFileSize {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import file is too large"})
+				return
+			}
+			file, err := fileHeader.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+				return
+			}
+			defer file.Close()
+
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, file); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+				return
+			}
+			text = buf.String()
+		}
+
+		if text == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Either a file or text is required"})
+			return
+		}
+
+		termDelimiter := "\t"
+		if req.Format == services.ExportFormatCSV {
+			termDelimiter = ","
+		}
+		cards, rowErrors = ic.importService.Parse(text, services.ImportOptions{
+			TermDelimiter: services.ParseDelimiter(req.TermDelimiter, termDelimiter),
+			RowDelimiter:  services.ParseDelimiter(req.RowDelimiter, "\n"),
+			Columns:       columns,
+			HasHeader:     req.HasHeader,
+		})
+	}
+
+	if len(cards) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No valid cards to import", "errors": rowErrors})
+		return
+	}
+	if len(cards) > maxImportCards {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("An import may contain at most %d cards", maxImportCards)})
+		return
+	}
+
+	now := time.Now()
+	cardSet := models.CardSet{
+		UserID:         userObjID,
+		Title:          req.Title,
+		Description:    req.Description,
+		Language:       req.Language,
+		Cards:          cards,
+		Progress:       models.StudyProgress{},
+		IsPublic:       false,
+		PhoneticStatus: models.PhoneticStatusEmpty,
+		Version:        1,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	result, err := ic.db.Collection("cardsets").InsertOne(ctx, cardSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create card set"})
+		return
+	}
+
+	cardSet.ID = result.InsertedID.(primitive.ObjectID)
+	setETag(c, cardSet.Version)
+	c.JSON(http.StatusCreated, models.ImportCardSetResponse{
+		CardSet:  cardSet,
+		Imported: len(cards),
+		Errors:   rowErrors,
+	})
+}
+
+// ExportCardSet downloads the cards of a card set as CSV, TSV or an Anki package
+func (ic *CardSetImportController) ExportCardSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", services.ExportFormatCSV)
+	if format != services.ExportFormatCSV && format != services.ExportFormatTSV && format != services.ExportFormatAPKG {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, tsv or apkg"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cardSet, ok := ic.findCardSet(ctx, c, cardSetObjID, userObjID)
+	if !ok {
+		return
+	}
+
+	if format == services.ExportFormatAPKG {
+		// Fetching images and writing the collection outlast the server's write timeout;
+		// the package bounds both itself, and stops fetching when the client goes away
+		http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+		var buf bytes.Buffer
+		if err := ic.ankiService.WritePackage(c.Request.Context(), &buf, cardSet, ic.mediaService.Fetch); err != nil {
+			log.Printf("Failed to export card set %s as Anki package: %v", cardSet.ID.Hex(), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export card set"})
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.ExportFilename(cardSet.Title, format)))
+		c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ic.importService.Export(&buf, cardSet.Cards, format); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export card set"})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == services.ExportFormatTSV {
+		contentType = "text/tab-separated-values; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.ExportFilename(cardSet.Title, format)))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// findCardSet loads a card set the user owns or collaborates on, writing the error response on failure
+func (ic *CardSetImportController) findCardSet(ctx context.Context, c *gin.Context, cardSetID, userID primitive.ObjectID) (*models.CardSet, bool) {
+	var cardSet models.CardSet
+	err := ic.db.Collection("cardsets").FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetID,
+		"deleted_at": nil,
+	}, userID, models.CollaboratorRoleViewer)).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return nil, false
+	}
+
+	return &cardSet, true
+}
+
+// importAnkiCards maps the notes of a package to cards and stores their packaged images.
+// Images that can't be stored are reported on the note but don't drop the card.
+func (ic *CardSetImportController) importAnkiCards(ctx context.Context, userID primitive.ObjectID, pkg *services.AnkiPackage, mapping map[string]string) ([]models.CardSetCard, []models.ImportRowError) {
+	imported, rowErrors := ic.ankiService.ToCards(pkg, mapping)
+
+	cards := make([]models.CardSetCard, 0, len(imported))
+	stored := map[string]string{}
+	for _, item := range imported {
+		card := item.Card
+		// Oversized imports are rejected by the caller, don't store their media
+		if item.ImageFile != "" && len(imported) <= maxImportCards {
+			url, ok := stored[item.ImageFile]
+			if !ok {
+				if media, err := pkg.Media(item.ImageFile); err == nil {
+					if id, err := ic.mediaService.Save(ctx, userID, *media); err == nil {
+						url = ic.mediaService.URL(id)
+					}
+				}
+				stored[item.ImageFile] = url
+			}
+			if url == "" {
+				rowErrors = append(rowErrors, models.ImportRowError{
+					Row:   item.Row,
+					Error: fmt.Sprintf("image %q could not be imported", item.ImageFile),
+				})
+			}
+			card.ImageURL = url
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rowErrors
+}
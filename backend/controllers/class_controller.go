@@ -0,0 +1,958 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"learn-backend/models"
+	"learn-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// cardMasteredLevel is the mastery level from which a card counts as mastered, as in the statistics overview
+const cardMasteredLevel = 80
+
+type ClassController struct {
+	db *mongo.Database
+}
+
+func NewClassController(db *mongo.Database) *ClassController {
+	return &ClassController{db: db}
+}
+
+// GetClasses lists the classes the user teaches or attends
+func (cc *ClassController) GetClasses(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := cc.db.Collection("classes").Find(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"teacher_id": userObjID},
+			bson.M{"students.user_id": userObjID},
+		},
+	}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch classes"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var classes []models.Class
+	if err := cursor.All(ctx, &classes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode classes"})
+		return
+	}
+
+	teacherIDs := make([]primitive.ObjectID, len(classes))
+	for i, class := range classes {
+		teacherIDs[i] = class.TeacherID
+	}
+	profiles, err := fetchProfiles(ctx, cc.db, teacherIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch classes"})
+		return
+	}
+
+	summaries := make([]models.ClassSummary, len(classes))
+	for i, class := range classes {
+		summaries[i] = classSummary(class, profiles[class.TeacherID], userObjID)
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// GetClass returns a class the user teaches or attends
+func (cc *ClassController) GetClass(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	class, userObjID, ok := cc.findClass(ctx, c, false)
+	if !ok {
+		return
+	}
+
+	profiles, err := fetchProfiles(ctx, cc.db, []primitive.ObjectID{class.TeacherID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch class"})
+		return
+	}
+
+	c.JSON(http.StatusOK, classSummary(*class, profiles[class.TeacherID], userObjID))
+}
+
+// CreateClass creates a class with a fresh join code
+func (cc *ClassController) CreateClass(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.CreateClassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code, err := utils.GenerateShortToken(models.ClassJoinCodeLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate join code"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	class := models.Class{
+		TeacherID:   userObjID,
+		Name:        req.Name,
+		Description: req.Description,
+		JoinCode:    code,
+		Students:    []models.ClassStudent{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	result, err := cc.db.Collection("classes").InsertOne(ctx, class)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create class"})
+		return
+	}
+
+	class.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, class)
+}
+
+// UpdateClass renames a class or changes its description
+func (cc *ClassController) UpdateClass(c *gin.Context) {
+	var req models.UpdateClassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	class, _, ok := cc.findClass(ctx, c, true)
+	if !ok {
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		set["name"] = *req.Name
+	}
+	if req.Description != nil {
+		set["description"] = *req.Description
+	}
+
+	var updated models.Class
+	err := cc.db.Collection("classes").FindOneAndUpdate(ctx,
+		bson.M{"_id": class.ID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Class not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update class"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteClass deletes a class and its assignments. The students keep their copies of the assigned sets.
+func (cc *ClassController) DeleteClass(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	class, _, ok := cc.findClass(ctx, c, true)
+	if !ok {
+		return
+	}
+
+	if _, err := cc.db.Collection("class_assignments").DeleteMany(ctx, bson.M{"class_id": class.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete assignments"})
+		return
+	}
+
+	if _, err := cc.db.Collection("classes").DeleteOne(ctx, bson.M{"_id": class.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete class"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Class deleted successfully"})
+}
+
+// ResetJoinCode replaces the join code of a class, so the old code stops working
+func (cc *ClassController) ResetJoinCode(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	class, _, ok := cc.findClass(ctx, c, true)
+	if !ok {
+		return
+	}
+
+	code, err := utils.GenerateShortToken(models.ClassJoinCodeLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate join code"})
+		return
+	}
+
+	if _, err := cc.db.Collection("classes").UpdateOne(ctx,
+		bson.M{"_id": class.ID},
+		bson.M{"$set": bson.M{"join_code": code, "updated_at": time.Now()}},
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset join code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"join_code": code})
+}
+
+// JoinClass adds the user to the class with the given join code
+func (cc *ClassController) JoinClass(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.JoinClassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var class models.Class
+	err = cc.db.Collection("classes").FindOne(ctx, bson.M{"join_code": req.Code}).Decode(&class)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid join code"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch class"})
+		return
+	}
+
+	if class.TeacherID == userObjID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You teach this class"})
+		return
+	}
+
+	// The filter makes joining a no-op when the user already attends the class
+	result, err := cc.db.Collection("classes").UpdateOne(ctx,
+		bson.M{"_id": class.ID, "join_code": req.Code, "students.user_id": bson.M{"$ne": userObjID}},
+		bson.M{"$push": bson.M{"students": models.ClassStudent{UserID: userObjID, JoinedAt: time.Now()}}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join class"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already joined this class"})
+		return
+	}
+
+	profiles, err := fetchProfiles(ctx, cc.db, []primitive.ObjectID{class.TeacherID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch class"})
+		return
+	}
+
+	c.JSON(http.StatusOK, classSummary(class, profiles[class.TeacherID], userObjID))
+}
+
+// LeaveClass removes the current user from a class they attend
+func (cc *ClassController) LeaveClass(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	classObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid class ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cc.removeStudent(ctx, c, bson.M{"_id": classObjID, "students.user_id": userObjID}, userObjID)
+}
+
+// RemoveStudent removes a student from a class
+func (cc *ClassController) RemoveStudent(c *gin.Context) {
+	studentObjID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	class, _, ok := cc.findClass(ctx, c, true)
+	if !ok {
+		return
+	}
+
+	cc.removeStudent(ctx, c, bson.M{"_id": class.ID, "students.user_id": studentObjID}, studentObjID)
+}
+
+// GetAssignments lists the assignments of a class, with the student's own copies for students
+func (cc *ClassController) GetAssignments(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	class, userObjID, ok := cc.findClass(ctx, c, false)
+	if !ok {
+		return
+	}
+
+	assignments, err := loadAssignments(ctx, cc.db, class.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignments"})
+		return
+	}
+
+	if class.TeacherID != userObjID {
+		copies, err := assignmentCopies(ctx, cc.db, []primitive.ObjectID{userObjID}, assignments)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignments"})
+			return
+		}
+		started := make(map[primitive.ObjectID]primitive.ObjectID, len(copies))
+		for _, cardSet := range copies {
+			if cardSet.DeletedAt == nil {
+				started[*cardSet.AssignmentID] = cardSet.ID
+			}
+		}
+		for i := range assignments {
+			if id, ok := started[assignments[i].ID]; ok {
+				assignments[i].StudentCardSetID = &id
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, assignments)
+}
+
+// CreateAssignment assigns a card set the teacher can view to a class
+func (cc *ClassController) CreateAssignment(c *gin.Context) {
+	var req models.CreateAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(req.CardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	class, userObjID, ok := cc.findClass(ctx, c, true)
+	if !ok {
+		return
+	}
+
+	var cardSet models.CardSet
+	err = cc.db.Collection("cardsets").FindOne(ctx,
+		withAccess(bson.M{"_id": cardSetObjID, "deleted_at": nil}, userObjID, models.CollaboratorRoleViewer),
+		options.FindOne().SetProjection(bson.M{"title": 1, "cards.id": 1}),
+	).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+
+	assignment := models.ClassAssignment{
+		ClassID:    class.ID,
+		CardSetID:  cardSet.ID,
+		AssignedBy: userObjID,
+		DueAt:      req.DueAt,
+		CreatedAt:  time.Now(),
+	}
+
+	result, err := cc.db.Collection("class_assignments").InsertOne(ctx, assignment)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Card set is already assigned to this class"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create assignment"})
+		return
+	}
+
+	assignment.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, models.ClassAssignmentView{
+		ClassAssignment: assignment,
+		Title:           cardSet.Title,
+		CardCount:       len(cardSet.Cards),
+	})
+}
+
+// UpdateAssignment changes the due date of an assignment
+func (cc *ClassController) UpdateAssignment(c *gin.Context) {
+	assignmentObjID, err := primitive.ObjectIDFromHex(c.Param("assignmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment ID"})
+		return
+	}
+
+	var req models.UpdateAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	class, _, ok := cc.findClass(ctx, c, true)
+	if !ok {
+		return
+	}
+
+	update := bson.M{"$unset": bson.M{"due_at": ""}}
+	if req.DueAt != nil {
+		update = bson.M{"$set": bson.M{"due_at": *req.DueAt}}
+	}
+
+	var assignment models.ClassAssignment
+	err = cc.db.Collection("class_assignments").FindOneAndUpdate(ctx,
+		bson.M{"_id": assignmentObjID, "class_id": class.ID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&assignment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update assignment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, assignment)
+}
+
+// DeleteAssignment removes an assignment from a class
+func (cc *ClassController) DeleteAssignment(c *gin.Context) {
+	assignmentObjID, err := primitive.ObjectIDFromHex(c.Param("assignmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	class, _, ok := cc.findClass(ctx, c, true)
+	if !ok {
+		return
+	}
+
+	result, err := cc.db.Collection("class_assignments").DeleteOne(ctx, bson.M{
+		"_id":      assignmentObjID,
+		"class_id": class.ID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete assignment"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Assignment deleted successfully"})
+}
+
+// StartAssignment copies the assigned card set into the student's card sets,
+// or returns the copy they already have, moving it out of the trash if needed
+func (cc *ClassController) StartAssignment(c *gin.Context) {
+	assignmentObjID, err := primitive.ObjectIDFromHex(c.Param("assignmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignment ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	class, userObjID, ok := cc.findClass(ctx, c, false)
+	if !ok {
+		return
+	}
+	if class.TeacherID == userObjID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only students can start assignments"})
+		return
+	}
+
+	var assignment models.ClassAssignment
+	err = cc.db.Collection("class_assignments").FindOne(ctx, bson.M{
+		"_id":      assignmentObjID,
+		"class_id": class.ID,
+	}).Decode(&assignment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignment"})
+		return
+	}
+
+	existing, err := cc.findAssignmentCopy(ctx, userObjID, assignment.ID)
+	if err == nil {
+		setETag(c, existing.Version)
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+
+	var source models.CardSet
+	err = cc.db.Collection("cardsets").FindOne(ctx, bson.M{
+		"_id":        assignment.CardSetID,
+		"deleted_at": nil,
+	}).Decode(&source)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Assigned card set no longer exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+
+	newCardSet := newCardSetCopy(source, userObjID)
+	newCardSet.AssignmentID = &assignment.ID
+	result, err := cc.db.Collection("cardsets").InsertOne(ctx, newCardSet)
+	if err != nil {
+		// A concurrent request created the copy first
+		if mongo.IsDuplicateKeyError(err) {
+			if existing, err := cc.findAssignmentCopy(ctx, userObjID, assignment.ID); err == nil {
+				setETag(c, existing.Version)
+				c.JSON(http.StatusOK, existing)
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy card set"})
+		return
+	}
+	newCardSet.ID = result.InsertedID.(primitive.ObjectID)
+
+	setETag(c, newCardSet.Version)
+	c.JSON(http.StatusCreated, newCardSet)
+}
+
+// findAssignmentCopy loads the student's copy of an assignment. A copy in the trash is restored,
+// since a student has only one copy per assignment.
+func (cc *ClassController) findAssignmentCopy(ctx context.Context, userID, assignmentID primitive.ObjectID) (*models.CardSet, error) {
+	var cardSet models.CardSet
+	err := cc.db.Collection("cardsets").FindOne(ctx, bson.M{
+		"user_id":             userID,
+		"class_assignment_id": assignmentID,
+	}).Decode(&cardSet)
+	if err != nil || cardSet.DeletedAt == nil {
+		return &cardSet, err
+	}
+
+	restored, err := restoreCardSet(ctx, cc.db, bson.M{"_id": cardSet.ID})
+	if err == nil {
+		return &restored, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Restored concurrently, or claimed by the trash purger
+	err = cc.db.Collection("cardsets").FindOne(ctx, bson.M{"_id": cardSet.ID}).Decode(&cardSet)
+	if err == nil && cardSet.DeletedAt != nil {
+		err = errors.New("assignment copy is being purged from the trash")
+	}
+	return &cardSet, err
+}
+
+// GetClassReport aggregates the study sessions and card mastery of every student per assignment
+func (cc *ClassController) GetClassReport(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	class, _, ok := cc.findClass(ctx, c, true)
+	if !ok {
+		return
+	}
+
+	assignments, err := loadAssignments(ctx, cc.db, class.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignments"})
+		return
+	}
+
+	studentIDs := make([]primitive.ObjectID, len(class.Students))
+	for i, student := range class.Students {
+		studentIDs[i] = student.UserID
+	}
+
+	profiles, err := fetchProfiles(ctx, cc.db, studentIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch students"})
+		return
+	}
+
+	copies, err := assignmentCopies(ctx, cc.db, studentIDs, assignments)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch student card sets"})
+		return
+	}
+
+	type progressKey struct {
+		student    primitive.ObjectID
+		assignment primitive.ObjectID
+	}
+	type progressTotals struct {
+		sessions      int
+		studyTime     int
+		correct       int
+		incorrect     int
+		cardsStudied  int
+		cardsMastered int
+		lastStudiedAt *time.Time
+	}
+
+	copyKeys := make(map[primitive.ObjectID]progressKey, len(copies))
+	copyIDs := make([]primitive.ObjectID, len(copies))
+	totals := make(map[progressKey]*progressTotals, len(copies))
+	for i, cardSet := range copies {
+		key := progressKey{student: cardSet.UserID, assignment: *cardSet.AssignmentID}
+		copyKeys[cardSet.ID] = key
+		copyIDs[i] = cardSet.ID
+		if totals[key] == nil {
+			totals[key] = &progressTotals{}
+		}
+	}
+
+	var sessions []struct {
+		CardSetID     primitive.ObjectID `bson:"_id"`
+		Sessions      int                `bson:"sessions"`
+		StudyTime     int                `bson:"study_time"`
+		Correct       int                `bson:"correct"`
+		Incorrect     int                `bson:"incorrect"`
+		LastStudiedAt time.Time          `bson:"last_studied_at"`
+	}
+	var mastery []struct {
+		CardSetID     primitive.ObjectID `bson:"_id"`
+		CardsStudied  int                `bson:"cards_studied"`
+		CardsMastered int                `bson:"cards_mastered"`
+	}
+
+	if len(copyIDs) > 0 {
+		match := bson.M{"$match": bson.M{
+			"user_id":    bson.M{"$in": studentIDs},
+			"cardset_id": bson.M{"$in": copyIDs},
+		}}
+
+		cursor, err := cc.db.Collection("study_sessions").Aggregate(ctx, bson.A{
+			match,
+			bson.M{"$group": bson.M{
+				"_id":             "$cardset_id",
+				"sessions":        bson.M{"$sum": 1},
+				"study_time":      bson.M{"$sum": "$duration"},
+				"correct":         bson.M{"$sum": "$correct"},
+				"incorrect":       bson.M{"$sum": "$incorrect"},
+				"last_studied_at": bson.M{"$max": "$end_time"},
+			}},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate study sessions"})
+			return
+		}
+		if err := cursor.All(ctx, &sessions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode study sessions"})
+			return
+		}
+
+		cursor, err = cc.db.Collection("card_mastery").Aggregate(ctx, bson.A{
+			match,
+			bson.M{"$group": bson.M{
+				"_id":           "$cardset_id",
+				"cards_studied": bson.M{"$sum": 1},
+				"cards_mastered": bson.M{"$sum": bson.M{
+					"$cond": bson.A{bson.M{"$gte": bson.A{"$mastery_level", cardMasteredLevel}}, 1, 0},
+				}},
+			}},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate card mastery"})
+			return
+		}
+		if err := cursor.All(ctx, &mastery); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode card mastery"})
+			return
+		}
+	}
+
+	for _, row := range sessions {
+		t := totals[copyKeys[row.CardSetID]]
+		t.sessions += row.Sessions
+		t.studyTime += row.StudyTime
+		t.correct += row.Correct
+		t.incorrect += row.Incorrect
+		if t.lastStudiedAt == nil || row.LastStudiedAt.After(*t.lastStudiedAt) {
+			lastStudiedAt := row.LastStudiedAt
+			t.lastStudiedAt = &lastStudiedAt
+		}
+	}
+	for _, row := range mastery {
+		t := totals[copyKeys[row.CardSetID]]
+		t.cardsStudied += row.CardsStudied
+		t.cardsMastered += row.CardsMastered
+	}
+
+	now := time.Now()
+	report := models.ClassReport{
+		ClassID:     class.ID,
+		Students:    make([]models.StudentReport, len(class.Students)),
+		Assignments: make([]models.AssignmentReport, len(assignments)),
+	}
+	for i, assignment := range assignments {
+		report.Assignments[i] = models.AssignmentReport{ClassAssignmentView: assignment}
+	}
+
+	for i, student := range class.Students {
+		studentReport := models.StudentReport{
+			Student:     profiles[student.UserID],
+			JoinedAt:    student.JoinedAt,
+			Assignments: make([]models.AssignmentProgress, len(assignments)),
+		}
+		correct, incorrect := 0, 0
+
+		for j, assignment := range assignments {
+			progress := models.AssignmentProgress{
+				AssignmentID: assignment.ID,
+				TotalCards:   assignment.CardCount,
+			}
+
+			if t := totals[progressKey{student: student.UserID, assignment: assignment.ID}]; t != nil {
+				progress.Started = true
+				progress.Sessions = t.sessions
+				progress.StudyTime = t.studyTime
+				progress.CardsStudied = t.cardsStudied
+				progress.CardsMastered = min(t.cardsMastered, assignment.CardCount)
+				progress.LastStudiedAt = t.lastStudiedAt
+				if t.correct+t.incorrect > 0 {
+					progress.Accuracy = float64(t.correct) / float64(t.correct+t.incorrect) * 100
+				}
+				correct += t.correct
+				incorrect += t.incorrect
+			}
+			if assignment.CardCount > 0 {
+				progress.Progress = float64(progress.CardsMastered) / float64(assignment.CardCount) * 100
+			}
+			progress.Overdue = assignment.DueAt != nil && assignment.DueAt.Before(now) && progress.Progress < 100
+
+			studentReport.Assignments[j] = progress
+			studentReport.Sessions += progress.Sessions
+			studentReport.StudyTime += progress.StudyTime
+			studentReport.CardsMastered += progress.CardsMastered
+
+			assignmentReport := &report.Assignments[j]
+			assignmentReport.AverageProgress += progress.Progress
+			if progress.Started {
+				assignmentReport.StudentsStarted++
+				assignmentReport.AverageAccuracy += progress.Accuracy
+			}
+			if progress.Progress >= 100 {
+				assignmentReport.StudentsCompleted++
+			}
+		}
+
+		if correct+incorrect > 0 {
+			studentReport.Accuracy = float64(correct) / float64(correct+incorrect) * 100
+		}
+		report.Students[i] = studentReport
+	}
+
+	for i := range report.Assignments {
+		if len(class.Students) > 0 {
+			report.Assignments[i].AverageProgress /= float64(len(class.Students))
+		}
+		if report.Assignments[i].StudentsStarted > 0 {
+			report.Assignments[i].AverageAccuracy /= float64(report.Assignments[i].StudentsStarted)
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// findClass loads a class the user teaches, or also attends unless teacherOnly, writing the error response on failure
+func (cc *ClassController) findClass(ctx context.Context, c *gin.Context, teacherOnly bool) (*models.Class, primitive.ObjectID, bool) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, userObjID, false
+	}
+
+	classObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid class ID"})
+		return nil, userObjID, false
+	}
+
+	filter := bson.M{"_id": classObjID, "teacher_id": userObjID}
+	if !teacherOnly {
+		filter = bson.M{"_id": classObjID, "$or": bson.A{
+			bson.M{"teacher_id": userObjID},
+			bson.M{"students.user_id": userObjID},
+		}}
+	}
+
+	var class models.Class
+	if err := cc.db.Collection("classes").FindOne(ctx, filter).Decode(&class); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Class not found"})
+			return nil, userObjID, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch class"})
+		return nil, userObjID, false
+	}
+
+	return &class, userObjID, true
+}
+
+// removeStudent pulls a student from the class matched by filter and writes the response
+func (cc *ClassController) removeStudent(ctx context.Context, c *gin.Context, filter bson.M, studentID primitive.ObjectID) {
+	result, err := cc.db.Collection("classes").UpdateOne(ctx, filter, bson.M{
+		"$pull": bson.M{"students": bson.M{"user_id": studentID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove student"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Student not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Student removed successfully"})
+}
+
+// classSummary builds the class list entry, hiding the join code and roster from students
+func classSummary(class models.Class, teacher models.PublicProfile, userID primitive.ObjectID) models.ClassSummary {
+	summary := models.ClassSummary{
+		Class:        class,
+		Teacher:      teacher,
+		StudentCount: len(class.Students),
+		IsTeacher:    class.TeacherID == userID,
+	}
+	if !summary.IsTeacher {
+		summary.JoinCode = ""
+		summary.Students = nil
+	}
+	return summary
+}
+
+// loadAssignments loads the assignments of a class with the title and size of their card sets
+func loadAssignments(ctx context.Context, db *mongo.Database, classID primitive.ObjectID) ([]models.ClassAssignmentView, error) {
+	cursor, err := db.Collection("class_assignments").Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"class_id": classID}},
+		bson.M{"$sort": bson.D{{Key: "created_at", Value: -1}}},
+		bson.M{"$lookup": bson.M{
+			"from": "cardsets",
+			"let":  bson.M{"cardset_id": "$cardset_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$cardset_id"}}}},
+				bson.M{"$project": bson.M{
+					"title":      1,
+					"card_count": bson.M{"$size": bson.M{"$ifNull": bson.A{"$cards", bson.A{}}}},
+				}},
+			},
+			"as": "cardset",
+		}},
+		bson.M{"$unwind": bson.M{"path": "$cardset", "preserveNullAndEmptyArrays": true}},
+		bson.M{"$addFields": bson.M{
+			"title":      "$cardset.title",
+			"card_count": bson.M{"$ifNull": bson.A{"$cardset.card_count", 0}},
+		}},
+		bson.M{"$project": bson.M{"cardset": 0}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	assignments := []models.ClassAssignmentView{}
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// assignmentCopies loads the students' copies of the assignments.
+// Trashed copies are included since the studying on them still happened.
+func assignmentCopies(ctx context.Context, db *mongo.Database, studentIDs []primitive.ObjectID, assignments []models.ClassAssignmentView) ([]models.CardSet, error) {
+	if len(studentIDs) == 0 || len(assignments) == 0 {
+		return nil, nil
+	}
+
+	assignmentIDs := make([]primitive.ObjectID, len(assignments))
+	for i, assignment := range assignments {
+		assignmentIDs[i] = assignment.ID
+	}
+
+	cursor, err := db.Collection("cardsets").Find(ctx,
+		bson.M{
+			"user_id":             bson.M{"$in": studentIDs},
+			"class_assignment_id": bson.M{"$in": assignmentIDs},
+		},
+		options.Find().SetProjection(bson.M{"user_id": 1, "class_assignment_id": 1, "deleted_at": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var copies []models.CardSet
+	if err := cursor.All(ctx, &copies); err != nil {
+		return nil, err
+	}
+	return copies, nil
+}
@@ -0,0 +1,430 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rolesAtLeast lists the collaborator roles that include each role
+var rolesAtLeast = map[string]bson.A{
+	models.CollaboratorRoleViewer: {models.CollaboratorRoleViewer, models.CollaboratorRoleEditor, models.CollaboratorRoleAdmin},
+	models.CollaboratorRoleEditor: {models.CollaboratorRoleEditor, models.CollaboratorRoleAdmin},
+	models.CollaboratorRoleAdmin:  {models.CollaboratorRoleAdmin},
+}
+
+// withAccess restricts a card set filter to sets the user owns or collaborates on with at least role
+func withAccess(filter bson.M, userID primitive.ObjectID, role string) bson.M {
+	filter["$or"] = bson.A{
+		bson.M{"user_id": userID},
+		bson.M{"collaborators": bson.M{"$elemMatch": bson.M{
+			"user_id":     userID,
+			"role":        bson.M{"$in": rolesAtLeast[role]},
+			"accepted_at": bson.M{"$ne": nil},
+		}}},
+	}
+	return filter
+}
+
+// hasAccess reports whether the user owns the card set or collaborates on it with at least role.
+// An empty role requires ownership.
+func hasAccess(cardSet models.CardSet, userID primitive.ObjectID, role string) bool {
+	if cardSet.UserID == userID {
+		return true
+	}
+	for _, collaborator := range cardSet.Collaborators {
+		if collaborator.UserID != userID || collaborator.AcceptedAt == nil {
+			continue
+		}
+		for _, granted := range rolesAtLeast[role] {
+			if collaborator.Role == granted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type CollaboratorController struct {
+	db *mongo.Database
+}
+
+func NewCollaboratorController(db *mongo.Database) *CollaboratorController {
+	return &CollaboratorController{db: db}
+}
+
+// GetCollaborators lists the collaborators of a card set, including pending invitations
+func (cc *CollaboratorController) GetCollaborators(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cardSet, _, ok := cc.findCardSet(ctx, c, models.CollaboratorRoleViewer)
+	if !ok {
+		return
+	}
+
+	ids := make([]primitive.ObjectID, len(cardSet.Collaborators))
+	for i, collaborator := range cardSet.Collaborators {
+		ids[i] = collaborator.UserID
+	}
+	profiles, err := fetchProfiles(ctx, cc.db, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collaborators"})
+		return
+	}
+
+	collaborators := make([]models.CollaboratorView, len(cardSet.Collaborators))
+	for i, collaborator := range cardSet.Collaborators {
+		collaborators[i] = models.CollaboratorView{Collaborator: collaborator, User: profiles[collaborator.UserID]}
+	}
+
+	c.JSON(http.StatusOK, collaborators)
+}
+
+// InviteCollaborator invites a user, found by email or username, to collaborate on a card set
+func (cc *CollaboratorController) InviteCollaborator(c *gin.Context) {
+	var req models.InviteCollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cardSet, userObjID, ok := cc.findCardSet(ctx, c, models.CollaboratorRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var invitee models.PublicProfile
+	err := cc.db.Collection("users").FindOne(ctx,
+		bson.M{"$or": bson.A{bson.M{"email": req.User}, bson.M{"username": req.User}}},
+		options.FindOne().SetProjection(bson.M{"username": 1, "full_name": 1, "avatar": 1}),
+	).Decode(&invitee)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	if invitee.ID == cardSet.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The owner can't be invited to their own card set"})
+		return
+	}
+
+	collaborator := models.Collaborator{
+		UserID:    invitee.ID,
+		Role:      req.Role,
+		InvitedBy: userObjID,
+		InvitedAt: time.Now(),
+	}
+
+	// The filter makes the invitation a no-op when the user is already a collaborator
+	result, err := cc.db.Collection("cardsets").UpdateOne(ctx,
+		bson.M{"_id": cardSet.ID, "collaborators.user_id": bson.M{"$ne": invitee.ID}},
+		bson.M{"$push": bson.M{"collaborators": collaborator}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite collaborator"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a collaborator"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CollaboratorView{Collaborator: collaborator, User: invitee})
+}
+
+// UpdateCollaborator changes the role of a collaborator
+func (cc *CollaboratorController) UpdateCollaborator(c *gin.Context) {
+	collaboratorObjID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateCollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cardSet, _, ok := cc.findCardSet(ctx, c, models.CollaboratorRoleAdmin)
+	if !ok {
+		return
+	}
+
+	result, err := cc.db.Collection("cardsets").UpdateOne(ctx,
+		bson.M{"_id": cardSet.ID, "collaborators.user_id": collaboratorObjID},
+		bson.M{"$set": bson.M{"collaborators.$.role": req.Role}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collaborator"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collaborator not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collaborator updated successfully"})
+}
+
+// RemoveCollaborator removes a collaborator or revokes an invitation.
+// Collaborators can also remove themselves, which declines a pending invitation.
+func (cc *CollaboratorController) RemoveCollaborator(c *gin.Context) {
+	collaboratorObjID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": cardSetObjID, "deleted_at": nil}
+	if collaboratorObjID == userObjID {
+		filter["collaborators.user_id"] = userObjID
+	} else {
+		withAccess(filter, userObjID, models.CollaboratorRoleAdmin)
+	}
+
+	result, err := cc.db.Collection("cardsets").UpdateOne(ctx, filter,
+		bson.M{"$pull": bson.M{"collaborators": bson.M{"user_id": collaboratorObjID}}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove collaborator"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+		return
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collaborator not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collaborator removed successfully"})
+}
+
+// AcceptInvitation accepts the current user's pending invitation to a card set
+func (cc *CollaboratorController) AcceptInvitation(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var cardSet models.CardSet
+	err = cc.db.Collection("cardsets").FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":        cardSetObjID,
+			"deleted_at": nil,
+			"collaborators": bson.M{"$elemMatch": bson.M{
+				"user_id":     userObjID,
+				"accepted_at": nil,
+			}},
+		},
+		bson.M{"$set": bson.M{"collaborators.$.accepted_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	setETag(c, cardSet.Version)
+	c.JSON(http.StatusOK, cardSet)
+}
+
+// GetInvitations lists the current user's pending collaboration invitations
+func (cc *CollaboratorController) GetInvitations(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"title": 1, "collaborators.$": 1})
+	cursor, err := cc.db.Collection("cardsets").Find(ctx, bson.M{
+		"deleted_at": nil,
+		"collaborators": bson.M{"$elemMatch": bson.M{
+			"user_id":     userObjID,
+			"accepted_at": nil,
+		}},
+	}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var cardSets []models.CardSet
+	if err := cursor.All(ctx, &cardSets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode invitations"})
+		return
+	}
+
+	inviters := make([]primitive.ObjectID, 0, len(cardSets))
+	for _, cardSet := range cardSets {
+		inviters = append(inviters, cardSet.Collaborators[0].InvitedBy)
+	}
+	profiles, err := fetchProfiles(ctx, cc.db, inviters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	invitations := make([]models.CardSetInvitation, len(cardSets))
+	for i, cardSet := range cardSets {
+		invitation := cardSet.Collaborators[0]
+		invitations[i] = models.CardSetInvitation{
+			CardSetID: cardSet.ID,
+			Title:     cardSet.Title,
+			Role:      invitation.Role,
+			InvitedBy: profiles[invitation.InvitedBy],
+			InvitedAt: invitation.InvitedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// GetSharedCardSets lists the card sets other users share with the current user
+func (cc *CollaboratorController) GetSharedCardSets(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	cursor, err := cc.db.Collection("cardsets").Find(ctx, bson.M{
+		"deleted_at": nil,
+		"collaborators": bson.M{"$elemMatch": bson.M{
+			"user_id":     userObjID,
+			"accepted_at": bson.M{"$ne": nil},
+		}},
+	}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card sets"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var cardSets []models.CardSet
+	if err := cursor.All(ctx, &cardSets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode card sets"})
+		return
+	}
+
+	if cardSets == nil {
+		cardSets = []models.CardSet{}
+	}
+
+	c.JSON(http.StatusOK, cardSets)
+}
+
+// findCardSet loads a card set the user has at least role on, writing the error response on failure
+func (cc *CollaboratorController) findCardSet(ctx context.Context, c *gin.Context, role string) (*models.CardSet, primitive.ObjectID, bool) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, userObjID, false
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return nil, userObjID, false
+	}
+
+	var cardSet models.CardSet
+	err = cc.db.Collection("cardsets").FindOne(ctx,
+		withAccess(bson.M{"_id": cardSetObjID, "deleted_at": nil}, userObjID, role),
+		options.FindOne().SetProjection(bson.M{"cards": 0}),
+	).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return nil, userObjID, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return nil, userObjID, false
+	}
+
+	return &cardSet, userObjID, true
+}
+
+// fetchProfiles loads the public profiles of users by id
+func fetchProfiles(ctx context.Context, db *mongo.Database, ids []primitive.ObjectID) (map[primitive.ObjectID]models.PublicProfile, error) {
+	profiles := make(map[primitive.ObjectID]models.PublicProfile, len(ids))
+	if len(ids) == 0 {
+		return profiles, nil
+	}
+
+	cursor, err := db.Collection("users").Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"username": 1, "full_name": 1, "avatar": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.PublicProfile
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		profiles[user.ID] = user
+	}
+	return profiles, nil
+}
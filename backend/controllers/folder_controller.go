@@ -0,0 +1,463 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"learn-backend/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FolderController struct {
+	db *mongo.Database
+}
+
+func NewFolderController(db *mongo.Database) *FolderController {
+	return &FolderController{db: db}
+}
+
+// GetFolders lists all folders of the user with the number of card sets directly in each.
+// The client builds the tree from parent_id.
+func (fc *FolderController) GetFolders(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := fc.db.Collection("folders").Find(ctx, bson.M{"user_id": userObjID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folders"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var folders []models.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode folders"})
+		return
+	}
+
+	countCursor, err := fc.db.Collection("cardsets").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"user_id":    userObjID,
+			"deleted_at": nil,
+			"folder_id":  bson.M{"$exists": true},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$folder_id", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count card sets"})
+		return
+	}
+	defer countCursor.Close(ctx)
+
+	var counts []struct {
+		FolderID primitive.ObjectID `bson:"_id"`
+		Count    int                `bson:"count"`
+	}
+	if err := countCursor.All(ctx, &counts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count card sets"})
+		return
+	}
+
+	countByFolder := make(map[primitive.ObjectID]int, len(counts))
+	for _, count := range counts {
+		countByFolder[count.FolderID] = count.Count
+	}
+	for i := range folders {
+		folders[i].CardSetCount = countByFolder[folders[i].ID]
+	}
+
+	if folders == nil {
+		folders = []models.Folder{}
+	}
+
+	c.JSON(http.StatusOK, folders)
+}
+
+// CreateFolder creates a folder at the root or inside another folder of the user
+func (fc *FolderController) CreateFolder(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	parentID, ok := resolveFolder(ctx, c, fc.db, userObjID, req.ParentID)
+	if !ok {
+		return
+	}
+	if parentID != nil {
+		depth, err := fc.folderDepth(ctx, userObjID, *parentID, primitive.NilObjectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folders"})
+			return
+		}
+		if depth >= models.MaxFolderDepth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Folders are nested too deeply"})
+			return
+		}
+	}
+
+	now := time.Now()
+	folder := models.Folder{
+		UserID:    userObjID,
+		ParentID:  parentID,
+		Name:      req.Name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := fc.db.Collection("folders").InsertOne(ctx, folder)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A folder with this name already exists here"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create folder"})
+		return
+	}
+
+	folder.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, folder)
+}
+
+// UpdateFolder renames a folder and/or moves it under another parent
+func (fc *FolderController) UpdateFolder(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	folderObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	var req models.UpdateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+
+	if req.Name != nil {
+		set["name"] = *req.Name
+	}
+	if req.ParentID != nil {
+		parentID, ok := resolveFolder(ctx, c, fc.db, userObjID, *req.ParentID)
+		if !ok {
+			return
+		}
+
+		if parentID == nil {
+			unset["parent_id"] = ""
+		} else {
+			// Walking up from the new parent must not reach the folder itself, and the
+			// folder's subtree must still fit below the new parent
+			depth, err := fc.folderDepth(ctx, userObjID, *parentID, folderObjID)
+			if err == errFolderCycle {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A folder can't be moved into itself"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folders"})
+				return
+			}
+			height, err := fc.folderHeight(ctx, userObjID, folderObjID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folders"})
+				return
+			}
+			if depth+height > models.MaxFolderDepth {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Folders are nested too deeply"})
+				return
+			}
+			set["parent_id"] = *parentID
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var folder models.Folder
+	err = fc.db.Collection("folders").FindOneAndUpdate(ctx,
+		bson.M{"_id": folderObjID, "user_id": userObjID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&folder)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A folder with this name already exists here"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update folder"})
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder removes a folder. Its card sets and subfolders move up to its parent.
+func (fc *FolderController) DeleteFolder(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	folderObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	foldersCollection := fc.db.Collection("folders")
+
+	var folder models.Folder
+	err = foldersCollection.FindOne(ctx, bson.M{"_id": folderObjID, "user_id": userObjID}).Decode(&folder)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folder"})
+		return
+	}
+
+	// Subfolders must not clash with the folders they move next to. The folder itself still
+	// exists while they move, so a subfolder with its name clashes too.
+	subfolderNames, err := foldersCollection.Distinct(ctx, "name", bson.M{"user_id": userObjID, "parent_id": folderObjID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folders"})
+		return
+	}
+	if len(subfolderNames) > 0 {
+		siblings := bson.M{"user_id": userObjID, "parent_id": nil, "name": bson.M{"$in": subfolderNames}}
+		if folder.ParentID != nil {
+			siblings["parent_id"] = *folder.ParentID
+		}
+		count, err := foldersCollection.CountDocuments(ctx, siblings)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folders"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "A subfolder has the same name as a folder in the parent"})
+			return
+		}
+	}
+
+	// Contents first, so a failure leaves the folder in place with nothing orphaned.
+	// Trashed sets move too, so restoring them doesn't point at a missing folder.
+	moveUp := bson.M{"$unset": bson.M{"folder_id": ""}}
+	moveFoldersUp := bson.M{"$unset": bson.M{"parent_id": ""}}
+	if folder.ParentID != nil {
+		moveUp = bson.M{"$set": bson.M{"folder_id": *folder.ParentID}}
+		moveFoldersUp = bson.M{"$set": bson.M{"parent_id": *folder.ParentID}}
+	}
+
+	if _, err := fc.db.Collection("cardsets").UpdateMany(ctx,
+		bson.M{"user_id": userObjID, "folder_id": folderObjID},
+		moveUp,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move card sets out of the folder"})
+		return
+	}
+
+	if _, err := foldersCollection.UpdateMany(ctx,
+		bson.M{"user_id": userObjID, "parent_id": folderObjID},
+		moveFoldersUp,
+	); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A subfolder has the same name as a folder in the parent"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move subfolders out of the folder"})
+		return
+	}
+
+	if _, err := foldersCollection.DeleteOne(ctx, bson.M{"_id": folderObjID, "user_id": userObjID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete folder"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted successfully"})
+}
+
+// MoveCardSets moves card sets of the user into a folder, or out of all folders.
+// Folders only organise sets, so moving doesn't change their version.
+func (fc *FolderController) MoveCardSets(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.MoveCardSetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cardSetIDs := make([]primitive.ObjectID, len(req.CardSetIDs))
+	for i, id := range req.CardSetIDs {
+		cardSetIDs[i], err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	folderID, ok := resolveFolder(ctx, c, fc.db, userObjID, req.FolderID)
+	if !ok {
+		return
+	}
+
+	update := bson.M{"$unset": bson.M{"folder_id": ""}}
+	if folderID != nil {
+		update = bson.M{"$set": bson.M{"folder_id": *folderID}}
+	}
+
+	result, err := fc.db.Collection("cardsets").UpdateMany(ctx, bson.M{
+		"_id":        bson.M{"$in": cardSetIDs},
+		"user_id":    userObjID,
+		"deleted_at": nil,
+	}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move card sets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"moved": result.MatchedCount})
+}
+
+// errFolderCycle is returned by folderDepth when the walk reaches the folder being moved
+var errFolderCycle = errors.New("folder cycle")
+
+// folderDepth counts the folders from id up to the root, id included.
+// It fails with errFolderCycle when the walk reaches moving.
+func (fc *FolderController) folderDepth(ctx context.Context, userID, id, moving primitive.ObjectID) (int, error) {
+	depth := 0
+	current := &id
+	for current != nil {
+		if *current == moving {
+			return 0, errFolderCycle
+		}
+		depth++
+		if depth > models.MaxFolderDepth {
+			return depth, nil
+		}
+
+		var folder models.Folder
+		err := fc.db.Collection("folders").FindOne(ctx,
+			bson.M{"_id": *current, "user_id": userID},
+			options.FindOne().SetProjection(bson.M{"parent_id": 1}),
+		).Decode(&folder)
+		if err != nil {
+			return 0, err
+		}
+		current = folder.ParentID
+	}
+	return depth, nil
+}
+
+// folderHeight counts the levels of the subtree rooted at id, id included.
+// It stops counting past MaxFolderDepth.
+func (fc *FolderController) folderHeight(ctx context.Context, userID, id primitive.ObjectID) (int, error) {
+	height := 1
+	level := []primitive.ObjectID{id}
+	for height <= models.MaxFolderDepth {
+		cursor, err := fc.db.Collection("folders").Find(ctx,
+			bson.M{"user_id": userID, "parent_id": bson.M{"$in": level}},
+			options.Find().SetProjection(bson.M{"_id": 1}),
+		)
+		if err != nil {
+			return 0, err
+		}
+		var children []models.Folder
+		if err := cursor.All(ctx, &children); err != nil {
+			return 0, err
+		}
+		if len(children) == 0 {
+			break
+		}
+
+		height++
+		level = level[:0]
+		for _, child := range children {
+			level = append(level, child.ID)
+		}
+	}
+	return height, nil
+}
+
+// resolveFolder parses the id of one of the user's folders, nil for an empty id,
+// writing the error response on failure
+func resolveFolder(ctx context.Context, c *gin.Context, db *mongo.Database, userID primitive.ObjectID, raw string) (*primitive.ObjectID, bool) {
+	if raw == "" {
+		return nil, true
+	}
+
+	folderID, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return nil, false
+	}
+
+	count, err := db.Collection("folders").CountDocuments(ctx, bson.M{"_id": folderID, "user_id": userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folder"})
+		return nil, false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		return nil, false
+	}
+
+	return &folderID, true
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/config"
+	"learn-backend/models"
+	"learn-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ForkController struct {
+	db          *mongo.Database
+	cfg         *config.Config
+	forkService *services.ForkService
+}
+
+func NewForkController(db *mongo.Database, cfg *config.Config) *ForkController {
+	return &ForkController{
+		db:          db,
+		cfg:         cfg,
+		forkService: services.NewForkService(),
+	}
+}
+
+// GetUpstreamDiff lists the changes of the source of a forked card set since it was last synced
+func (fc *ForkController) GetUpstreamDiff(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fork, upstream, ok := fc.findFork(ctx, c)
+	if !ok {
+		return
+	}
+
+	setETag(c, fork.Version)
+	c.JSON(http.StatusOK, fc.forkService.Diff(*fork, *upstream))
+}
+
+// SyncUpstream merges the added, changed and removed source cards into a forked card set.
+// Cards edited locally are kept, and the previous state is saved as a revision.
+func (fc *ForkController) SyncUpstream(c *gin.Context) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fork, upstream, ok := fc.findFork(ctx, c)
+	if !ok {
+		return
+	}
+
+	// The merge is computed from the loaded fork, so it must be the version the client saw
+	if fork.Version != version {
+		respondVersionMismatch(ctx, c, fc.db, fork.ID, fork.UserID, "")
+		return
+	}
+
+	cards, origin, diff := fc.forkService.Merge(*fork, *upstream)
+
+	cardSetsCollection := fc.db.Collection("cardsets")
+
+	var previous models.CardSet
+	err := cardSetsCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": fork.ID, "user_id": fork.UserID, "deleted_at": nil, "version": versionFilter(version)},
+		bson.M{
+			"$set": bson.M{
+				"cards":       cards,
+				"forked_from": origin,
+				"updated_at":  time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondVersionMismatch(ctx, c, fc.db, fork.ID, fork.UserID, "")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync card set"})
+		return
+	}
+
+	saveRevision(ctx, fc.db, previous, fc.cfg.RevisionRetention)
+
+	var cardSet models.CardSet
+	err = cardSetsCollection.FindOne(ctx, bson.M{"_id": fork.ID}).Decode(&cardSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch synced card set"})
+		return
+	}
+
+	setETag(c, cardSet.Version)
+	c.JSON(http.StatusOK, models.SyncUpstreamResponse{CardSet: cardSet, Diff: diff})
+}
+
+// findFork loads one of the user's forked card sets and its source, writing the error response on failure.
+// The source must still be in the global library or belong to the user.
+func (fc *ForkController) findFork(ctx context.Context, c *gin.Context) (*models.CardSet, *models.CardSet, bool) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, nil, false
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return nil, nil, false
+	}
+
+	cardSetsCollection := fc.db.Collection("cardsets")
+
+	var fork models.CardSet
+	err = cardSetsCollection.FindOne(ctx, bson.M{
+		"_id":        cardSetObjID,
+		"user_id":    userObjID,
+		"deleted_at": nil,
+	}).Decode(&fork)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return nil, nil, false
+	}
+
+	if fork.ForkedFrom == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Card set is not a fork"})
+		return nil, nil, false
+	}
+
+	// Keep in line with tracksUpstream
+	var upstream models.CardSet
+	err = cardSetsCollection.FindOne(ctx, bson.M{
+		"_id":        fork.ForkedFrom.CardSetID,
+		"deleted_at": nil,
+		"$or": bson.A{
+			bson.M{"is_public": true, "hidden": bson.M{"$ne": true}},
+			bson.M{"user_id": userObjID},
+		},
+	}).Decode(&upstream)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source card set is no longer available"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch source card set"})
+		return nil, nil, false
+	}
+
+	return &fork, &upstream, true
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/models"
+	"learn-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type GradingController struct {
+	db             *mongo.Database
+	gradingService *services.GradingService
+}
+
+func NewGradingController(db *mongo.Database) *GradingController {
+	return &GradingController{
+		db:             db,
+		gradingService: services.NewGradingService(),
+	}
+}
+
+// CheckAnswer grades a typed answer against one card of a set
+func (gc *GradingController) CheckAnswer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+	cardID := c.Param("cardId")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.CheckAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var cardSet models.CardSet
+	err = gc.db.Collection("cardsets").FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+	}, userObjID, models.CollaboratorRoleViewer)).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+
+	for _, card := range cardSet.Cards {
+		if card.ID != cardID {
+			continue
+		}
+
+		expected := card.Define
+		if req.AnswerWith == models.AnswerWithTerm {
+			expected = card.Terminology
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"result":         gc.gradingService.Check(req.Answer, expected),
+			"correct_answer": expected,
+		})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+}
@@ -0,0 +1,397 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/models"
+	"learn-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type LearnController struct {
+	db           *mongo.Database
+	learnService *services.LearnService
+}
+
+func NewLearnController(db *mongo.Database) *LearnController {
+	return &LearnController{
+		db:           db,
+		learnService: services.NewLearnService(),
+	}
+}
+
+// CreateLearnSession starts a new learn session for a card set
+func (lc *LearnController) CreateLearnSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.CreateLearnSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.AnswerWith == "" {
+		req.AnswerWith = models.AnswerWithDefinition
+	}
+	if req.RoundSize == 0 {
+		req.RoundSize = services.DefaultLearnRoundSize
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var cardSet models.CardSet
+	err = lc.db.Collection("cardsets").FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+	}, userObjID, models.CollaboratorRoleViewer)).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+
+	if len(cardSet.Cards) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Card set has no cards"})
+		return
+	}
+
+	now := time.Now()
+	session := models.LearnSession{
+		UserID:     userObjID,
+		CardSetID:  cardSetObjID,
+		Status:     models.LearnSessionStatusActive,
+		AnswerWith: req.AnswerWith,
+		RoundSize:  req.RoundSize,
+		Attempts:   []models.CardAttempt{},
+		StartTime:  now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	lc.learnService.NewSession(&session, cardSet.Cards)
+
+	result, err := lc.db.Collection("learn_sessions").InsertOne(ctx, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create learn session"})
+		return
+	}
+
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetLearnSession returns the current state of a learn session
+func (lc *LearnController) GetLearnSession(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, ok := lc.findSession(ctx, c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// GetNextQuestion returns the next question of a learn session
+func (lc *LearnController) GetNextQuestion(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, ok := lc.findSession(ctx, c)
+	if !ok {
+		return
+	}
+
+	if session.Status == models.LearnSessionStatusCompleted {
+		c.JSON(http.StatusOK, gin.H{"completed": true, "session": session})
+		return
+	}
+
+	// Already asked and not answered yet: serve the same question again
+	if session.CurrentQuestion != nil {
+		c.JSON(http.StatusOK, gin.H{"completed": false, "round": session.Round, "question": session.CurrentQuestion})
+		return
+	}
+
+	var cardSet models.CardSet
+	err := lc.db.Collection("cardsets").FindOne(ctx, withAccess(bson.M{"_id": session.CardSetID, "deleted_at": nil}, session.UserID, models.CollaboratorRoleViewer)).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+
+	question := lc.learnService.NextQuestion(session, cardSet.Cards)
+	if question == nil {
+		if err := lc.completeSession(ctx, session); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete learn session"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"completed": true, "session": session})
+		return
+	}
+
+	// Only store the question if nobody else asked one in the meantime
+	session.UpdatedAt = time.Now()
+	result, err := lc.db.Collection("learn_sessions").UpdateOne(ctx, bson.M{
+		"_id":              session.ID,
+		"status":           models.LearnSessionStatusActive,
+		"current_question": bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{
+		"round":            session.Round,
+		"round_card_ids":   session.RoundCardIDs,
+		"cards":            session.Cards,
+		"current_question": question,
+		"updated_at":       session.UpdatedAt,
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update learn session"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Learn session was updated concurrently, please retry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"completed": false, "round": session.Round, "question": question})
+}
+
+// SubmitAnswer grades the answer to the current question and advances the session
+func (lc *LearnController) SubmitAnswer(c *gin.Context) {
+	var req models.LearnAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, ok := lc.findSession(ctx, c)
+	if !ok {
+		return
+	}
+
+	if session.Status == models.LearnSessionStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Learn session is already completed"})
+		return
+	}
+
+	question := session.CurrentQuestion
+	if question == nil || question.ID != req.QuestionID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Question is not the current question of this session"})
+		return
+	}
+
+	correct := lc.learnService.Grade(question, req.Answer)
+
+	now := time.Now()
+	timeSpent := req.TimeSpent
+	if elapsed := int(now.Sub(question.AskedAt).Seconds()); timeSpent == 0 || timeSpent > elapsed {
+		timeSpent = elapsed
+	}
+	session.Attempts = append(session.Attempts, models.CardAttempt{
+		CardID:      question.CardID,
+		Correct:     correct,
+		TimeSpent:   timeSpent,
+		UserAnswer:  req.Answer,
+		AttemptedAt: now,
+	})
+
+	completed := lc.learnService.ApplyAnswer(session, correct)
+
+	stage := models.LearnStageNew
+	for _, state := range session.Cards {
+		if state.CardID == question.CardID {
+			stage = state.Stage
+			break
+		}
+	}
+
+	// The question id in the filter guards against the same answer being graded twice
+	session.UpdatedAt = now
+	result, err := lc.db.Collection("learn_sessions").UpdateOne(ctx, bson.M{
+		"_id":                 session.ID,
+		"current_question.id": question.ID,
+	}, bson.M{
+		"$set": bson.M{
+			"cards":      session.Cards,
+			"attempts":   session.Attempts,
+			"updated_at": session.UpdatedAt,
+		},
+		"$unset": bson.M{"current_question": ""},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update learn session"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Question was already answered"})
+		return
+	}
+
+	if completed {
+		if err := lc.completeSession(ctx, session); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete learn session"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.LearnAnswerResponse{
+		Correct:       correct,
+		CorrectAnswer: question.Answer,
+		Stage:         stage,
+		Session:       session,
+	})
+}
+
+// findSession loads the learn session from the route params, writing the error response on failure
+func (lc *LearnController) findSession(ctx context.Context, c *gin.Context) (*models.LearnSession, bool) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return nil, false
+	}
+
+	sessionObjID, err := primitive.ObjectIDFromHex(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid learn session ID"})
+		return nil, false
+	}
+
+	var session models.LearnSession
+	err = lc.db.Collection("learn_sessions").FindOne(ctx, bson.M{
+		"_id":        sessionObjID,
+		"user_id":    userObjID,
+		"cardset_id": cardSetObjID,
+	}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Learn session not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch learn session"})
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// completeSession records the finished learn session as a study session.
+// The study session is inserted first, so a failed completion is retried by the next request.
+func (lc *LearnController) completeSession(ctx context.Context, session *models.LearnSession) error {
+	if session.StudySessionID != nil {
+		return nil
+	}
+
+	now := time.Now()
+
+	correct := 0
+	incorrect := 0
+	studied := 0
+	for _, attempt := range session.Attempts {
+		if attempt.Correct {
+			correct++
+		} else {
+			incorrect++
+		}
+		studied += min(attempt.TimeSpent, maxAttemptTimeSpent)
+	}
+
+	totalCards := len(session.Attempts)
+	accuracy := 0.0
+	if totalCards > 0 {
+		accuracy = float64(correct) / float64(totalCards) * 100
+	}
+
+	// Count the time spent answering, not the time the session was left open
+	endTime := now
+	firstAnswer := session.StartTime
+	if totalCards > 0 {
+		first := session.Attempts[0]
+		firstAnswer = first.AttemptedAt.Add(-time.Duration(first.TimeSpent) * time.Second)
+		endTime = session.Attempts[totalCards-1].AttemptedAt
+	}
+	startTime, duration := sessionWindow(firstAnswer, endTime, time.Duration(studied)*time.Second)
+
+	studySession := models.StudySession{
+		UserID:         session.UserID,
+		CardSetID:      session.CardSetID,
+		Mode:           models.StudyModeLearn,
+		LearnSessionID: &session.ID,
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Duration:       duration,
+		TotalCards:     totalCards,
+		Correct:        correct,
+		Incorrect:      incorrect,
+		Accuracy:       accuracy,
+		Attempts:       session.Attempts,
+		StatsJob:       models.NewStatsJob(),
+		CreatedAt:      now,
+	}
+
+	insertResult, err := lc.db.Collection("study_sessions").InsertOne(ctx, studySession)
+	if err == nil {
+		studySession.ID = insertResult.InsertedID.(primitive.ObjectID)
+	} else {
+		// An earlier completion recorded the session but didn't mark it completed
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		var existing models.StudySession
+		if err := lc.db.Collection("study_sessions").FindOne(ctx, bson.M{"learn_session_id": session.ID}).Decode(&existing); err != nil {
+			return err
+		}
+		studySession = existing
+	}
+
+	_, err = lc.db.Collection("learn_sessions").UpdateOne(ctx, bson.M{
+		"_id":    session.ID,
+		"status": models.LearnSessionStatusActive,
+	}, bson.M{"$set": bson.M{
+		"status":           models.LearnSessionStatusCompleted,
+		"end_time":         studySession.EndTime,
+		"study_session_id": studySession.ID,
+		"updated_at":       now,
+	}})
+	if err != nil {
+		return err
+	}
+	session.Status = models.LearnSessionStatusCompleted
+	session.EndTime = &studySession.EndTime
+	session.StudySessionID = &studySession.ID
+	return nil
+}
@@ -53,6 +53,7 @@ func (lrc *LoginOrRegisterController) LoginOrRegister(c *gin.Context) {
 			user.ID.Hex(),
 			user.Email,
 			user.Username,
+			user.Role,
 			lrc.cfg.JWTSecret,
 			expiry,
 		)
@@ -145,6 +146,7 @@ func (lrc *LoginOrRegisterController) LoginOrRegister(c *gin.Context) {
 			newUser.ID.Hex(),
 			newUser.Email,
 			newUser.Username,
+			newUser.Role,
 			lrc.cfg.JWTSecret,
 			expiry,
 		)
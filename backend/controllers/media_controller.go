@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/config"
+	"learn-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+type MediaController struct {
+	mediaService *services.MediaService
+}
+
+func NewMediaController(db *mongo.Database, cfg *config.Config) *MediaController {
+	return &MediaController{mediaService: services.NewMediaService(db, cfg.PublicURL)}
+}
+
+// GetMedia serves a stored card image. It is public so <img> tags can load it without a token.
+func (mc *MediaController) GetMedia(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	file, err := mc.mediaService.Open(ctx, id)
+	if err != nil {
+		if err == gridfs.ErrFileNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch media"})
+		return
+	}
+
+	// Media files are never modified, so they can be cached for good
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
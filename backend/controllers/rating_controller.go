@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/config"
+	"learn-backend/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RatingController struct {
+	db  *mongo.Database
+	cfg *config.Config
+}
+
+func NewRatingController(db *mongo.Database, cfg *config.Config) *RatingController {
+	return &RatingController{db: db, cfg: cfg}
+}
+
+// RateCardSet creates or replaces the user's rating of a public card set
+// and keeps the average and count stored on the set up to date
+func (rc *RatingController) RateCardSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.RateCardSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !rc.checkPublicCardSet(ctx, c, cardSetObjID, userObjID, "You can't rate your own card set") {
+		return
+	}
+
+	now := time.Now()
+	var previous models.CardSetRating
+	err = rc.db.Collection("cardset_ratings").FindOneAndUpdate(ctx,
+		bson.M{"cardset_id": cardSetObjID, "user_id": userObjID},
+		bson.M{
+			"$set": bson.M{
+				"stars":      req.Stars,
+				"comment":    req.Comment,
+				"updated_at": now,
+			},
+			"$setOnInsert": bson.M{"created_at": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+
+	created := err == mongo.ErrNoDocuments
+	if err != nil && !created {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Rating is already being saved"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save rating"})
+		return
+	}
+
+	// Apply the difference to the stored totals, so concurrent ratings of other users aren't lost
+	sumDelta, countDelta := req.Stars-previous.Stars, 0
+	if created {
+		sumDelta, countDelta = req.Stars, 1
+	}
+
+	var totals struct {
+		RatingAverage float64 `bson:"rating_average"`
+		RatingCount   int     `bson:"rating_count"`
+	}
+	err = rc.db.Collection("cardsets").FindOneAndUpdate(ctx,
+		bson.M{"_id": cardSetObjID},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"rating_sum":   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating_sum", 0}}, sumDelta}},
+				"rating_count": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating_count", 0}}, countDelta}},
+			}}},
+			{{Key: "$set", Value: bson.M{
+				"rating_average": bson.M{"$cond": bson.A{
+					bson.M{"$gt": bson.A{"$rating_count", 0}},
+					bson.M{"$round": bson.A{bson.M{"$divide": bson.A{"$rating_sum", "$rating_count"}}, 2}},
+					0,
+				}},
+			}}},
+		},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(bson.M{"rating_average": 1, "rating_count": 1}),
+	).Decode(&totals)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card set rating"})
+		return
+	}
+
+	rating := models.CardSetRating{
+		ID:        previous.ID,
+		CardSetID: cardSetObjID,
+		UserID:    userObjID,
+		Stars:     req.Stars,
+		Comment:   req.Comment,
+		CreatedAt: previous.CreatedAt,
+		UpdatedAt: now,
+	}
+	status := http.StatusOK
+	if created {
+		rating.CreatedAt = now
+		status = http.StatusCreated
+	}
+
+	c.JSON(status, gin.H{
+		"rating":         rating,
+		"rating_average": totals.RatingAverage,
+		"rating_count":   totals.RatingCount,
+	})
+}
+
+// GetReviews lists the ratings of a card set, newest first, with their authors
+func (rc *RatingController) GetReviews(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	match := bson.M{"cardset_id": cardSetObjID}
+	if raw := c.Query("cursor"); raw != "" {
+		after, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		match["_id"] = bson.M{"$lt": after}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Reviews are visible wherever the set is: in the library, or to its owner
+	count, err := rc.db.Collection("cardsets").CountDocuments(ctx, bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+		"$or": bson.A{
+			bson.M{"is_public": true, "hidden": bson.M{"$ne": true}},
+			bson.M{"user_id": userObjID},
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+		return
+	}
+
+	cursor, err := rc.db.Collection("cardset_ratings").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}},
+		{{Key: "$limit", Value: limit + 1}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"user": bson.M{"$let": bson.M{
+				"vars": bson.M{"u": bson.M{"$arrayElemAt": bson.A{"$user", 0}}},
+				"in": bson.M{
+					"_id":       "$user_id",
+					"username":  "$$u.username",
+					"full_name": "$$u.full_name",
+					"avatar":    "$$u.avatar",
+				},
+			}},
+		}}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.CardSetReview
+	if err := cursor.All(ctx, &items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode reviews"})
+		return
+	}
+
+	page := models.CardSetReviewPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = page.Items[limit-1].ID.Hex()
+	}
+	if page.Items == nil {
+		page.Items = []models.CardSetReview{}
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ReportCardSet flags a public card set. Once ReportThreshold users have reported it,
+// the set is hidden from the global library.
+func (rc *RatingController) ReportCardSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.ReportCardSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !rc.checkPublicCardSet(ctx, c, cardSetObjID, userObjID, "You can't report your own card set") {
+		return
+	}
+
+	report := models.CardSetReport{
+		CardSetID: cardSetObjID,
+		UserID:    userObjID,
+		Reason:    req.Reason,
+		Details:   req.Details,
+		CreatedAt: time.Now(),
+	}
+	result, err := rc.db.Collection("cardset_reports").InsertOne(ctx, report)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already reported this card set"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report card set"})
+		return
+	}
+	report.ID = result.InsertedID.(primitive.ObjectID)
+
+	// Count the report and hide the set in the same update, so concurrent reports can't skip the threshold
+	var counts struct {
+		ReportCount int  `bson:"report_count"`
+		Hidden      bool `bson:"hidden"`
+	}
+	err = rc.db.Collection("cardsets").FindOneAndUpdate(ctx,
+		bson.M{"_id": cardSetObjID},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"report_count": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$report_count", 0}}, 1}},
+			}}},
+			{{Key: "$set", Value: bson.M{
+				"hidden": bson.M{"$or": bson.A{
+					bson.M{"$ifNull": bson.A{"$hidden", false}},
+					bson.M{"$gte": bson.A{"$report_count", rc.cfg.ReportThreshold}},
+				}},
+			}}},
+		},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(bson.M{"report_count": 1, "hidden": 1}),
+	).Decode(&counts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card set"})
+		return
+	}
+
+	if counts.Hidden && counts.ReportCount == rc.cfg.ReportThreshold {
+		log.Printf("Card set %s hidden from the global library after %d reports", cardSetObjID.Hex(), counts.ReportCount)
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// checkPublicCardSet makes sure the card set is in the global library and not owned by the user,
+// writing the error response on failure
+func (rc *RatingController) checkPublicCardSet(ctx context.Context, c *gin.Context, cardSetID, userID primitive.ObjectID, ownSetError string) bool {
+	var cardSet struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+	}
+	err := rc.db.Collection("cardsets").FindOne(ctx,
+		bson.M{
+			"_id":        cardSetID,
+			"is_public":  true,
+			"deleted_at": nil,
+			"hidden":     bson.M{"$ne": true},
+		},
+		options.FindOne().SetProjection(bson.M{"user_id": 1}),
+	).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found or not public"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return false
+	}
+
+	if cardSet.UserID == userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": ownSetError})
+		return false
+	}
+
+	return true
+}
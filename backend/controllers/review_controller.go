@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReviewController struct {
+	db *mongo.Database
+}
+
+func NewReviewController(db *mongo.Database) *ReviewController {
+	return &ReviewController{db: db}
+}
+
+// GetDueReviews returns the cards due for review across all of the user's card sets
+func (rc *ReviewController) GetDueReviews(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	opts := options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := rc.db.Collection("card_mastery").Find(ctx, bson.M{
+		"user_id": userObjID,
+		"due_at":  bson.M{"$lte": now},
+	}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch due reviews"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var masteries []models.CardMastery
+	if err := cursor.All(ctx, &masteries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode due reviews"})
+		return
+	}
+
+	// Load the card sets referenced by the due cards
+	cardSetIDs := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, mastery := range masteries {
+		if !seen[mastery.CardSetID] {
+			seen[mastery.CardSetID] = true
+			cardSetIDs = append(cardSetIDs, mastery.CardSetID)
+		}
+	}
+
+	cardSets := map[primitive.ObjectID]models.CardSet{}
+	if len(cardSetIDs) > 0 {
+		setCursor, err := rc.db.Collection("cardsets").Find(ctx, withAccess(bson.M{
+			"_id":        bson.M{"$in": cardSetIDs},
+			"deleted_at": nil,
+		}, userObjID, models.CollaboratorRoleViewer))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card sets"})
+			return
+		}
+		defer setCursor.Close(ctx)
+
+		var sets []models.CardSet
+		if err := setCursor.All(ctx, &sets); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode card sets"})
+			return
+		}
+		for _, set := range sets {
+			cardSets[set.ID] = set
+		}
+	}
+
+	// Skip cards whose set or card no longer exists
+	dueCards := []models.DueCard{}
+	for _, mastery := range masteries {
+		cardSet, ok := cardSets[mastery.CardSetID]
+		if !ok {
+			continue
+		}
+		for _, card := range cardSet.Cards {
+			if card.ID == mastery.CardID {
+				dueCards = append(dueCards, models.DueCard{
+					CardSetID:    cardSet.ID,
+					CardSetTitle: cardSet.Title,
+					Card:         card,
+					Mastery:      mastery,
+				})
+				break
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"due_count": len(dueCards),
+		"cards":     dueCards,
+	})
+}
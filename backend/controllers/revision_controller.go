@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/config"
+	"learn-backend/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RevisionController struct {
+	db  *mongo.Database
+	cfg *config.Config
+}
+
+func NewRevisionController(db *mongo.Database, cfg *config.Config) *RevisionController {
+	return &RevisionController{db: db, cfg: cfg}
+}
+
+// GetRevisions lists the revisions of a card set, newest first, without their cards
+func (rc *RevisionController) GetRevisions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if !rc.checkAccess(ctx, c, cardSetObjID, userObjID, models.CollaboratorRoleViewer) {
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "revision", Value: -1}}).
+		SetProjection(bson.M{"cards": 0})
+	cursor, err := rc.db.Collection("cardset_revisions").Find(ctx, bson.M{
+		"cardset_id": cardSetObjID,
+	}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []models.CardSetRevision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode revisions"})
+		return
+	}
+
+	if revisions == nil {
+		revisions = []models.CardSetRevision{}
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetRevision returns one full revision of a card set
+func (rc *RevisionController) GetRevision(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	revision, _, ok := rc.findRevision(ctx, c, models.CollaboratorRoleViewer)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// RestoreRevision overwrites a card set with one of its revisions.
+// The current state is saved as a revision first, so a restore can be undone too.
+func (rc *RevisionController) RestoreRevision(c *gin.Context) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	revision, userObjID, ok := rc.findRevision(ctx, c, models.CollaboratorRoleEditor)
+	if !ok {
+		return
+	}
+
+	cardSetsCollection := rc.db.Collection("cardsets")
+
+	var previous models.CardSet
+	err := cardSetsCollection.FindOneAndUpdate(
+		ctx,
+		withAccess(bson.M{"_id": revision.CardSetID, "deleted_at": nil, "version": versionFilter(version)}, userObjID, models.CollaboratorRoleEditor),
+		bson.M{
+			"$set": bson.M{
+				"title":       revision.Title,
+				"description": revision.Description,
+				"language":    revision.Language,
+				"cards":       revision.Cards,
+				"updated_at":  time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondVersionMismatch(ctx, c, rc.db, revision.CardSetID, userObjID, models.CollaboratorRoleEditor)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	saveRevision(ctx, rc.db, previous, rc.cfg.RevisionRetention)
+
+	var cardSet models.CardSet
+	err = cardSetsCollection.FindOne(ctx, bson.M{"_id": revision.CardSetID}).Decode(&cardSet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch restored card set"})
+		return
+	}
+
+	setETag(c, cardSet.Version)
+	c.JSON(http.StatusOK, cardSet)
+}
+
+// findRevision loads the revision from the route params of a card set the user has at least role on,
+// writing the error response on failure
+func (rc *RevisionController) findRevision(ctx context.Context, c *gin.Context, role string) (*models.CardSetRevision, primitive.ObjectID, bool) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, userObjID, false
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return nil, userObjID, false
+	}
+
+	rev, err := strconv.ParseInt(c.Param("rev"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return nil, userObjID, false
+	}
+
+	if !rc.checkAccess(ctx, c, cardSetObjID, userObjID, role) {
+		return nil, userObjID, false
+	}
+
+	var revision models.CardSetRevision
+	err = rc.db.Collection("cardset_revisions").FindOne(ctx, bson.M{
+		"cardset_id": cardSetObjID,
+		"revision":   rev,
+	}).Decode(&revision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return nil, userObjID, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revision"})
+		return nil, userObjID, false
+	}
+
+	return &revision, userObjID, true
+}
+
+// checkAccess verifies the user has at least role on the card set, writing the error response on failure.
+// Revisions are saved under the owner, so access goes through the card set rather than the revision.
+func (rc *RevisionController) checkAccess(ctx context.Context, c *gin.Context, cardSetID, userID primitive.ObjectID, role string) bool {
+	count, err := rc.db.Collection("cardsets").CountDocuments(ctx,
+		withAccess(bson.M{"_id": cardSetID, "deleted_at": nil}, userID, role),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+		return false
+	}
+	return true
+}
+
+// saveRevision stores a snapshot of a card set and prunes revisions beyond the retention count.
+// Failures are logged only: losing a snapshot must not fail the update itself.
+func saveRevision(ctx context.Context, db *mongo.Database, cardSet models.CardSet, retention int) {
+	revisionsCollection := db.Collection("cardset_revisions")
+
+	revision := models.CardSetRevision{
+		CardSetID:   cardSet.ID,
+		UserID:      cardSet.UserID,
+		Revision:    cardSet.Version,
+		Title:       cardSet.Title,
+		Description: cardSet.Description,
+		Language:    cardSet.Language,
+		Cards:       cardSet.Cards,
+		CardCount:   len(cardSet.Cards),
+		CreatedAt:   time.Now(),
+	}
+
+	// Upsert on the revision number so a retried write doesn't store the snapshot twice
+	_, err := revisionsCollection.UpdateOne(ctx,
+		bson.M{"cardset_id": cardSet.ID, "revision": cardSet.Version},
+		bson.M{"$setOnInsert": revision},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("Failed to save revision %d of card set %s: %v", cardSet.Version, cardSet.ID.Hex(), err)
+		return
+	}
+
+	if retention <= 0 {
+		return
+	}
+
+	// Find the oldest revision to keep and delete everything older
+	var oldestKept models.CardSetRevision
+	err = revisionsCollection.FindOne(ctx,
+		bson.M{"cardset_id": cardSet.ID},
+		options.FindOne().
+			SetSort(bson.D{{Key: "revision", Value: -1}}).
+			SetSkip(int64(retention-1)).
+			SetProjection(bson.M{"revision": 1}),
+	).Decode(&oldestKept)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Printf("Failed to prune revisions of card set %s: %v", cardSet.ID.Hex(), err)
+		}
+		return
+	}
+
+	_, err = revisionsCollection.DeleteMany(ctx, bson.M{
+		"cardset_id": cardSet.ID,
+		"revision":   bson.M{"$lt": oldestKept.Revision},
+	})
+	if err != nil {
+		log.Printf("Failed to prune revisions of card set %s: %v", cardSet.ID.Hex(), err)
+	}
+}
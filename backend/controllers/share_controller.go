@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/models"
+	"learn-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// shareTokenLength is the length of share link tokens
+const shareTokenLength = 10
+
+type ShareController struct {
+	db *mongo.Database
+}
+
+func NewShareController(db *mongo.Database) *ShareController {
+	return &ShareController{db: db}
+}
+
+// CreateShare creates a share link for one of the user's card sets
+func (sc *ShareController) CreateShare(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.CreateShareRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := sc.db.Collection("cardsets").CountDocuments(ctx, bson.M{
+		"_id":        cardSetObjID,
+		"user_id":    userObjID,
+		"deleted_at": nil,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+		return
+	}
+
+	token, err := utils.GenerateShortToken(shareTokenLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate share token"})
+		return
+	}
+
+	now := time.Now()
+	share := models.CardSetShare{
+		Token:     token,
+		CardSetID: cardSetObjID,
+		UserID:    userObjID,
+		MaxUses:   req.MaxUses,
+		UseCount:  0,
+		CreatedAt: now,
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := now.Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		share.ExpiresAt = &expiresAt
+	}
+	if req.Password != "" {
+		hash, err := utils.HashPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+		share.PasswordHash = hash
+		share.HasPassword = true
+	}
+
+	result, err := sc.db.Collection("cardset_shares").InsertOne(ctx, share)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share"})
+		return
+	}
+
+	share.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, share)
+}
+
+// GetShares lists the share links of one of the user's card sets
+func (sc *ShareController) GetShares(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := sc.db.Collection("cardset_shares").Find(ctx, bson.M{
+		"cardset_id": cardSetObjID,
+		"user_id":    userObjID,
+	}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shares"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var shares []models.CardSetShare
+	if err := cursor.All(ctx, &shares); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode shares"})
+		return
+	}
+
+	if shares == nil {
+		shares = []models.CardSetShare{}
+	}
+
+	c.JSON(http.StatusOK, shares)
+}
+
+// DeleteShare revokes a share link
+func (sc *ShareController) DeleteShare(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	shareObjID, err := primitive.ObjectIDFromHex(c.Param("shareId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := sc.db.Collection("cardset_shares").DeleteOne(ctx, bson.M{
+		"_id":        shareObjID,
+		"cardset_id": cardSetObjID,
+		"user_id":    userObjID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share revoked successfully"})
+}
+
+// GetSharePreview shows a shared card set without authentication.
+// For password protected shares the cards are only included with the X-Share-Password header.
+func (sc *ShareController) GetSharePreview(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	share, cardSet, ok := sc.findShare(ctx, c)
+	if !ok {
+		return
+	}
+
+	preview := models.SharePreview{
+		Token:            share.Token,
+		Title:            cardSet.Title,
+		Description:      cardSet.Description,
+		Language:         cardSet.Language,
+		CardCount:        len(cardSet.Cards),
+		RequiresPassword: share.HasPassword,
+		ExpiresAt:        share.ExpiresAt,
+	}
+
+	if password := c.GetHeader("X-Share-Password"); !share.HasPassword || (password != "" && utils.CheckPassword(password, share.PasswordHash)) {
+		preview.Cards = cardSet.Cards
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// ImportShare copies a shared card set into the user's card sets and counts the use
+func (sc *ShareController) ImportShare(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.ImportShareRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	share, cardSet, ok := sc.findShare(ctx, c)
+	if !ok {
+		return
+	}
+
+	if share.HasPassword && !utils.CheckPassword(req.Password, share.PasswordHash) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Incorrect share password"})
+		return
+	}
+
+	// Count the use atomically so max_uses holds under concurrent imports
+	result, err := sc.db.Collection("cardset_shares").UpdateOne(ctx, bson.M{
+		"_id": share.ID,
+		"$or": bson.A{
+			bson.M{"max_uses": bson.M{"$exists": false}},
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$use_count", "$max_uses"}}},
+		},
+	}, bson.M{"$inc": bson.M{"use_count": 1}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update share"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusGone, gin.H{"error": "Share link has reached its maximum number of uses"})
+		return
+	}
+
+	newCardSet, err := copyCardSet(ctx, sc.db, *cardSet, userObjID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import card set"})
+		return
+	}
+
+	setETag(c, newCardSet.Version)
+	c.JSON(http.StatusCreated, newCardSet)
+}
+
+// findShare loads a usable share and its card set by token, writing the error response on failure
+func (sc *ShareController) findShare(ctx context.Context, c *gin.Context) (*models.CardSetShare, *models.CardSet, bool) {
+	var share models.CardSetShare
+	err := sc.db.Collection("cardset_shares").FindOne(ctx, bson.M{"token": c.Param("token")}).Decode(&share)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share"})
+		return nil, nil, false
+	}
+
+	if share.ExpiresAt != nil && share.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "Share link has expired"})
+		return nil, nil, false
+	}
+	if share.MaxUses > 0 && share.UseCount >= share.MaxUses {
+		c.JSON(http.StatusGone, gin.H{"error": "Share link has reached its maximum number of uses"})
+		return nil, nil, false
+	}
+
+	var cardSet models.CardSet
+	err = sc.db.Collection("cardsets").FindOne(ctx, bson.M{
+		"_id":        share.CardSetID,
+		"deleted_at": nil,
+	}).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shared card set no longer exists"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return nil, nil, false
+	}
+
+	return &share, &cardSet, true
+}
@@ -2,10 +2,12 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"time"
 
 	"learn-backend/models"
+	"learn-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,30 +17,68 @@ import (
 )
 
 type StatisticsController struct {
-	DB *mongo.Database
+	DB      *mongo.Database
+	streaks *services.StreakService
+	stats   *services.StatisticsService
 }
 
 func NewStatisticsController(db *mongo.Database) *StatisticsController {
-	return &StatisticsController{DB: db}
+	return &StatisticsController{
+		DB:      db,
+		streaks: services.NewStreakService(),
+		stats:   services.NewStatisticsService(db),
+	}
 }
 
+// Limits on recorded study sessions, so forged sessions can't inflate statistics
+const (
+	maxSessionDuration  = 6 * time.Hour
+	maxSessionAttempts  = 2000
+	maxAttemptTimeSpent = 600 // seconds
+	minAttemptDuration  = 300 * time.Millisecond
+	sessionClockSkew    = 5 * time.Minute
+	maxIdempotencyKey   = 128
+)
+
+// Defaults and limits of statistics timeseries
+const (
+	defaultTimeseriesDays = 30
+	maxTimeseriesBuckets  = 800
+)
+
 // CreateStudySessionRequest represents the request body for recording a study session
 type CreateStudySessionRequest struct {
-	CardSetID string                 `json:"cardset_id" binding:"required"`
-	Mode      models.StudyMode       `json:"mode" binding:"required"`
-	StartTime string                 `json:"start_time" binding:"required"`
-	EndTime   string                 `json:"end_time" binding:"required"`
-	Attempts  []models.CardAttempt   `json:"attempts" binding:"required"`
+	CardSetID string               `json:"cardset_id" binding:"required"`
+	Mode      models.StudyMode     `json:"mode" binding:"required"`
+	StartTime string               `json:"start_time" binding:"required"`
+	EndTime   string               `json:"end_time" binding:"required"`
+	Attempts  []models.CardAttempt `json:"attempts" binding:"required,min=1"`
 }
 
-// RecordStudySession creates a new study session record and updates statistics
+// RecordStudySession creates a new study session record and updates statistics.
+// The Idempotency-Key header is required; replaying a key returns the session recorded first.
 func (sc *StatisticsController) RecordStudySession(c *gin.Context) {
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" || len(idempotencyKey) > maxIdempotencyKey {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key header required"})
+		return
+	}
+
 	var req CreateStudySessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if !req.Mode.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode"})
+		return
+	}
+	if len(req.Attempts) > maxSessionAttempts {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many attempts in one session"})
+		return
+	}
+
 	// Get user_id from context (set by auth middleware)
 	userID, _ := c.Get("user_id")
 	userObjID, _ := primitive.ObjectIDFromHex(userID.(string))
@@ -50,15 +90,24 @@ func (sc *StatisticsController) RecordStudySession(c *gin.Context) {
 		return
 	}
 
-	// Verify cardset belongs to user
+	// Verify the user can study the cardset
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// A retried request returns the session it already recorded
+	if existing, found, err := sc.findSessionByKey(ctx, userObjID, idempotencyKey); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record study session"})
+		return
+	} else if found {
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+
 	var cardSet models.CardSet
-	err = sc.DB.Collection("cardsets").FindOne(ctx, bson.M{
-		"_id":     cardSetObjID,
-		"user_id": userObjID,
-	}).Decode(&cardSet)
+	err = sc.DB.Collection("cardsets").FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+	}, userObjID, models.CollaboratorRoleViewer)).Decode(&cardSet)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			c.JSON(http.StatusNotFound, gin.H{"error": "CardSet not found"})
@@ -81,11 +130,54 @@ func (sc *StatisticsController) RecordStudySession(c *gin.Context) {
 		return
 	}
 
+	// Reject sessions that can't have happened
+	now := time.Now()
+	if !endTime.After(startTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_time must be after start_time"})
+		return
+	}
+	if endTime.After(now.Add(sessionClockSkew)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_time is in the future"})
+		return
+	}
+	if endTime.Sub(startTime) > maxSessionDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Session is too long"})
+		return
+	}
+	if endTime.Sub(startTime) < time.Duration(len(req.Attempts))*minAttemptDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Session is too short for its number of attempts"})
+		return
+	}
+
+	// The same session replayed under another key overlaps the recorded one. Sessions of other
+	// sets or modes may run in parallel on another device.
+	overlapping, err := sc.DB.Collection("study_sessions").CountDocuments(ctx, bson.M{
+		"user_id":    userObjID,
+		"cardset_id": cardSetObjID,
+		"mode":       req.Mode,
+		"end_time":   bson.M{"$gt": startTime},
+		"start_time": bson.M{"$lt": endTime},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record study session"})
+		return
+	}
+	if overlapping > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Session overlaps a session already recorded"})
+		return
+	}
+
+	attempts, ok := sanitizeAttempts(req.Attempts, cardSet.Cards, startTime, endTime)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Attempts must be at cards of the card set"})
+		return
+	}
+
 	// Calculate statistics
 	duration := int(endTime.Sub(startTime).Seconds())
 	correct := 0
 	incorrect := 0
-	for _, attempt := range req.Attempts {
+	for _, attempt := range attempts {
 		if attempt.Correct {
 			correct++
 		} else {
@@ -93,7 +185,7 @@ func (sc *StatisticsController) RecordStudySession(c *gin.Context) {
 		}
 	}
 
-	totalCards := len(req.Attempts)
+	totalCards := len(attempts)
 	accuracy := 0.0
 	if totalCards > 0 {
 		accuracy = float64(correct) / float64(totalCards) * 100
@@ -101,35 +193,38 @@ func (sc *StatisticsController) RecordStudySession(c *gin.Context) {
 
 	// Create study session
 	session := models.StudySession{
-		UserID:    userObjID,
-		CardSetID: cardSetObjID,
-		Mode:      req.Mode,
-		StartTime: startTime,
-		EndTime:   endTime,
-		Duration:  duration,
-		TotalCards: totalCards,
-		Correct:   correct,
-		Incorrect: incorrect,
-		Accuracy:  accuracy,
-		Attempts:  req.Attempts,
-		CreatedAt: time.Now(),
-	}
-
-	// Insert session
+		UserID:         userObjID,
+		CardSetID:      cardSetObjID,
+		Mode:           req.Mode,
+		StartTime:      startTime,
+		EndTime:        endTime,
+		Duration:       duration,
+		TotalCards:     totalCards,
+		Correct:        correct,
+		Incorrect:      incorrect,
+		Accuracy:       accuracy,
+		Attempts:       attempts,
+		IdempotencyKey: idempotencyKey,
+		StatsJob:       models.NewStatsJob(),
+		CreatedAt:      now,
+	}
+
+	// Insert session; its stats job has the statistics worker apply it
 	result, err := sc.DB.Collection("study_sessions").InsertOne(ctx, session)
 	if err != nil {
+		// A concurrent retry recorded the session first
+		if mongo.IsDuplicateKeyError(err) {
+			if existing, found, err := sc.findSessionByKey(ctx, userObjID, idempotencyKey); err == nil && found {
+				c.JSON(http.StatusOK, existing)
+				return
+			}
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record study session"})
 		return
 	}
 
 	session.ID = result.InsertedID.(primitive.ObjectID)
 
-	// Update user statistics asynchronously (non-blocking)
-	go sc.updateUserStatistics(userObjID, session)
-
-	// Update card mastery asynchronously
-	go sc.updateCardMastery(userObjID, cardSetObjID, req.Attempts)
-
 	c.JSON(http.StatusCreated, session)
 }
 
@@ -155,8 +250,19 @@ func (sc *StatisticsController) GetUserStatistics(c *gin.Context) {
 		return
 	}
 
+	// The stored streak is as of the last session; it may have lapsed since
+	if !userStats.LastStudyDate.IsZero() {
+		loc := sc.stats.UserLocation(ctx, userObjID)
+		userStats.CurrentStreak = sc.streaks.CurrentStreak(
+			userStats.CurrentStreak,
+			sc.streaks.Day(userStats.LastStudyDate, loc),
+			userStats.StreakFreezes,
+			sc.streaks.Day(time.Now(), loc),
+		)
+	}
+
 	// Get performance by mode
-	performanceByMode, err := sc.getPerformanceByMode(ctx, userObjID)
+	performanceByMode, err := sc.getPerformanceByMode(ctx, bson.M{"user_id": userObjID})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate performance by mode"})
 		return
@@ -209,12 +315,12 @@ func (sc *StatisticsController) GetCardSetStatistics(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Verify ownership
+	// Owners and collaborators study the set, each with their own statistics
 	var cardSet models.CardSet
-	err = sc.DB.Collection("cardsets").FindOne(ctx, bson.M{
-		"_id":     cardSetObjID,
-		"user_id": userObjID,
-	}).Decode(&cardSet)
+	err = sc.DB.Collection("cardsets").FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+	}, userObjID, models.CollaboratorRoleViewer)).Decode(&cardSet)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			c.JSON(http.StatusNotFound, gin.H{"error": "CardSet not found"})
@@ -244,6 +350,7 @@ func (sc *StatisticsController) GetCardSetStatistics(c *gin.Context) {
 
 	// Get card mastery for this card set
 	cursor, err = sc.DB.Collection("card_mastery").Find(ctx, bson.M{
+		"user_id":    userObjID,
 		"cardset_id": cardSetObjID,
 	})
 	if err != nil {
@@ -258,281 +365,530 @@ func (sc *StatisticsController) GetCardSetStatistics(c *gin.Context) {
 		return
 	}
 
+	performanceByMode, err := sc.getPerformanceByMode(ctx, bson.M{
+		"user_id":    userObjID,
+		"cardset_id": cardSetObjID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch performance by mode"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"cardset":      cardSet,
-		"sessions":     sessions,
-		"card_mastery": cardMastery,
+		"cardset":             cardSet,
+		"sessions":            sessions,
+		"card_mastery":        cardMastery,
+		"performance_by_mode": performanceByMode,
 	})
 }
 
-// Helper functions
+// GetCardSetMastery classifies every card of a set into a mastery bucket based on the
+// user's recent answers, with bucket counts
+func (sc *StatisticsController) GetCardSetMastery(c *gin.Context) {
+	cardSetObjID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cardset_id"})
+		return
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
 
-func (sc *StatisticsController) updateUserStatistics(userID primitive.ObjectID, session models.StudySession) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Calculate daily stats
-	sessionDate := time.Date(session.StartTime.Year(), session.StartTime.Month(), session.StartTime.Day(), 0, 0, 0, 0, time.UTC)
+	var cardSet models.CardSet
+	err = sc.DB.Collection("cardsets").FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+	}, userObjID, models.CollaboratorRoleViewer)).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "CardSet not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify cardset"})
+		}
+		return
+	}
+
+	cursor, err := sc.DB.Collection("card_mastery").Find(ctx, bson.M{
+		"user_id":    userObjID,
+		"cardset_id": cardSetObjID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card mastery"})
+		return
+	}
 
-	// Upsert user statistics
-	filter := bson.M{"user_id": userID}
-	update := bson.M{
-		"$inc": bson.M{
-			"total_study_time": session.Duration,
-			"total_sessions":   1,
-			"total_attempts":   session.TotalCards,
-		},
-		"$set": bson.M{
-			"last_study_date": session.StartTime,
-			"updated_at":      time.Now(),
-		},
+	var masteries []models.CardMastery
+	if err := cursor.All(ctx, &masteries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode card mastery"})
+		return
+	}
+	byCard := make(map[string]*models.CardMastery, len(masteries))
+	for i := range masteries {
+		byCard[masteries[i].CardID] = &masteries[i]
 	}
 
-	opts := options.Update().SetUpsert(true)
-	sc.DB.Collection("user_statistics").UpdateOne(ctx, filter, update, opts)
+	// Cards in set order; mastery of cards removed from the set is left out
+	response := models.CardSetMasteryResponse{
+		CardSetID: cardSetObjID,
+		Cards:     make([]models.CardMasteryDetail, 0, len(cardSet.Cards)),
+	}
+	for _, card := range cardSet.Cards {
+		mastery := byCard[card.ID]
+		bucket := sc.stats.MasteryBucket(mastery)
+		switch bucket {
+		case models.MasteryBucketNew:
+			response.Buckets.New++
+		case models.MasteryBucketLearning:
+			response.Buckets.Learning++
+		case models.MasteryBucketReviewing:
+			response.Buckets.Reviewing++
+		case models.MasteryBucketMastered:
+			response.Buckets.Mastered++
+		}
 
-	// Update daily stats
-	sc.updateDailyStats(userID, sessionDate, session)
+		response.Cards = append(response.Cards, models.CardMasteryDetail{
+			Card:           card,
+			Bucket:         bucket,
+			RecentAccuracy: sc.stats.RecentAccuracy(mastery),
+			Mastery:        mastery,
+		})
+	}
 
-	// Recalculate overall accuracy and streak
-	sc.recalculateUserStats(userID)
+	c.JSON(http.StatusOK, response)
 }
 
-func (sc *StatisticsController) updateDailyStats(userID primitive.ObjectID, date time.Time, session models.StudySession) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Get existing daily stats
-	var userStats models.UserStatistics
-	err := sc.DB.Collection("user_statistics").FindOne(ctx, bson.M{"user_id": userID}).Decode(&userStats)
+// GetTimeseries sums up the user's sessions per day, week or month between two dates.
+// Dates are YYYY-MM-DD in the user's timezone and default to the last 30 days;
+// the sessions can be narrowed down to a card set and a study mode.
+func (sc *StatisticsController) GetTimeseries(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Find or create daily stat
-	found := false
-	for i, stat := range userStats.DailyStats {
-		if stat.Date.Equal(date) {
-			userStats.DailyStats[i].SessionsCount++
-			userStats.DailyStats[i].CardsStudied += session.TotalCards
-			userStats.DailyStats[i].TimeSpent += session.Duration
-			// Recalculate average accuracy
-			totalAccuracy := userStats.DailyStats[i].Accuracy * float64(userStats.DailyStats[i].SessionsCount-1)
-			userStats.DailyStats[i].Accuracy = (totalAccuracy + session.Accuracy) / float64(userStats.DailyStats[i].SessionsCount)
-			found = true
-			break
-		}
+	granularity := models.TimeseriesGranularity(c.DefaultQuery("granularity", string(models.TimeseriesDay)))
+	if granularity != models.TimeseriesDay && granularity != models.TimeseriesWeek && granularity != models.TimeseriesMonth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be day, week or month"})
+		return
 	}
 
-	if !found {
-		newDailyStat := models.DailyStats{
-			Date:          date,
-			SessionsCount: 1,
-			CardsStudied:  session.TotalCards,
-			TimeSpent:     session.Duration,
-			Accuracy:      session.Accuracy,
+	filter := bson.M{"user_id": userObjID}
+	if cardSetID := c.Query("cardset_id"); cardSetID != "" {
+		cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cardset_id"})
+			return
 		}
-		userStats.DailyStats = append(userStats.DailyStats, newDailyStat)
+		filter["cardset_id"] = cardSetObjID
 	}
-
-	// Keep only last 90 days
-	if len(userStats.DailyStats) > 90 {
-		userStats.DailyStats = userStats.DailyStats[len(userStats.DailyStats)-90:]
+	if mode := models.StudyMode(c.Query("mode")); mode != "" {
+		if !mode.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode"})
+			return
+		}
+		filter["mode"] = mode
 	}
 
-	// Update
-	sc.DB.Collection("user_statistics").UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{
-		"$set": bson.M{"daily_stats": userStats.DailyStats},
-	})
-}
-
-func (sc *StatisticsController) recalculateUserStats(userID primitive.ObjectID) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Get all sessions
-	cursor, err := sc.DB.Collection("study_sessions").Find(ctx, bson.M{"user_id": userID})
-	if err != nil {
+	loc := sc.stats.UserLocation(ctx, userObjID)
+	to := sc.streaks.Day(time.Now(), loc)
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(time.DateOnly, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format"})
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -(defaultTimeseriesDays - 1))
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(time.DateOnly, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format"})
+			return
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
 		return
 	}
-	defer cursor.Close(ctx)
-
-	var sessions []models.StudySession
-	cursor.All(ctx, &sessions)
 
-	if len(sessions) == 0 {
-		return
+	// Every bucket from the one holding from to the one holding to, so charts get no gaps.
+	// The range widens to whole buckets, so the first and last ones aren't partial.
+	buckets := make([]models.TimeseriesBucket, 0)
+	index := make(map[string]int)
+	end := timeseriesBucketStart(from, granularity)
+	for start := end; !start.After(to); start = end {
+		if len(buckets) == maxTimeseriesBuckets {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Too many buckets, narrow the range or use a coarser granularity"})
+			return
+		}
+		index[start.Format(time.DateOnly)] = len(buckets)
+		buckets = append(buckets, models.TimeseriesBucket{Date: start})
+		end = timeseriesNextBucket(start, granularity)
 	}
+	from = buckets[0].Date
+	to = end.AddDate(0, 0, -1)
 
-	// Calculate overall accuracy
-	totalCorrect := 0
-	totalAttempts := 0
-	for _, session := range sessions {
-		totalCorrect += session.Correct
-		totalAttempts += session.TotalCards
+	// The dates are local calendar days; sessions are matched on their local start day
+	filter["start_time"] = bson.M{
+		"$gte": time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc),
+		"$lt":  time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc),
 	}
 
-	overallAccuracy := 0.0
-	if totalAttempts > 0 {
-		overallAccuracy = float64(totalCorrect) / float64(totalAttempts) * 100
+	trunc := bson.M{"date": "$start_time", "unit": granularity, "timezone": loc.String()}
+	if granularity == models.TimeseriesWeek {
+		trunc["startOfWeek"] = "monday"
+	}
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$group": bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format":   "%Y-%m-%d",
+				"date":     bson.M{"$dateTrunc": trunc},
+				"timezone": loc.String(),
+			}},
+			"sessions":      bson.M{"$sum": 1},
+			"study_time":    bson.M{"$sum": "$duration"},
+			"cards_studied": bson.M{"$sum": "$total_cards"},
+			"correct":       bson.M{"$sum": "$correct"},
+		}},
 	}
 
-	// Calculate streak
-	currentStreak, longestStreak := sc.calculateStreaks(userID)
+	cursor, err := sc.DB.Collection("study_sessions").Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate sessions"})
+		return
+	}
+	defer cursor.Close(ctx)
 
-	// Count unique cards studied
-	uniqueCards := sc.countUniqueCards(userID)
+	for cursor.Next(ctx) {
+		var result struct {
+			Start        string `bson:"_id"`
+			Sessions     int    `bson:"sessions"`
+			StudyTime    int    `bson:"study_time"`
+			CardsStudied int    `bson:"cards_studied"`
+			Correct      int    `bson:"correct"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode sessions"})
+			return
+		}
+		i, ok := index[result.Start]
+		if !ok {
+			continue
+		}
 
-	// Get card mastery counts
-	masteredCount, learningCount, newCount := sc.getCardMasteryCounts(userID)
+		bucket := &buckets[i]
+		bucket.Sessions = result.Sessions
+		bucket.StudyTime = result.StudyTime
+		bucket.CardsStudied = result.CardsStudied
+		if result.CardsStudied > 0 {
+			bucket.Accuracy = float64(result.Correct) / float64(result.CardsStudied) * 100
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate sessions"})
+		return
+	}
 
-	// Update user statistics
-	sc.DB.Collection("user_statistics").UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{
-		"$set": bson.M{
-			"overall_accuracy":    overallAccuracy,
-			"current_streak":      currentStreak,
-			"longest_streak":      longestStreak,
-			"total_cards_studied": uniqueCards,
-			"cards_mastered":      masteredCount,
-			"cards_learning":      learningCount,
-			"cards_new":           newCount,
-			"updated_at":          time.Now(),
-		},
+	c.JSON(http.StatusOK, models.TimeseriesResponse{
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+		Timezone:    loc.String(),
+		Buckets:     buckets,
 	})
 }
 
-func (sc *StatisticsController) calculateStreaks(userID primitive.ObjectID) (int, int) {
+// FreezeStreak freezes a day, so not studying on it doesn't break the streak.
+// Users get MaxStreakFreezesPerMonth frozen days per calendar month, and can't freeze past days.
+func (sc *StatisticsController) FreezeStreak(c *gin.Context) {
+	var req models.FreezeStreakRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	day, err := time.Parse(time.DateOnly, req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		return
+	}
+
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	today := sc.streaks.Day(time.Now(), sc.stats.UserLocation(ctx, userObjID))
+	if day.Before(today) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Past days can't be frozen"})
+		return
+	}
+	if day.After(today.AddDate(0, 1, 0)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Days can be frozen at most a month ahead"})
+		return
+	}
+
 	var userStats models.UserStatistics
-	err := sc.DB.Collection("user_statistics").FindOne(ctx, bson.M{"user_id": userID}).Decode(&userStats)
-	if err != nil {
-		return 0, 0
+	err = sc.DB.Collection("user_statistics").FindOne(ctx, bson.M{"user_id": userObjID}).Decode(&userStats)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch statistics"})
+		return
+	}
+	for _, frozen := range userStats.StreakFreezes {
+		if frozen.Equal(day) {
+			c.JSON(http.StatusOK, gin.H{"streak_freezes": userStats.StreakFreezes})
+			return
+		}
+	}
+	used := sc.streaks.FreezesInMonth(userStats.StreakFreezes, day)
+	if used >= services.MaxStreakFreezesPerMonth {
+		c.JSON(http.StatusConflict, gin.H{"error": "No streak freezes left for this month"})
+		return
+	}
+
+	// The guard on the month's count keeps concurrent requests within the allowance;
+	// when it fails the upsert collides with the existing statistics on user_id
+	monthStart := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	filter := bson.M{
+		"user_id": userObjID,
+		"$expr": bson.M{"$lt": bson.A{
+			bson.M{"$size": bson.M{"$filter": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$streak_freezes", bson.A{}}},
+				"cond": bson.M{"$and": bson.A{
+					bson.M{"$gte": bson.A{"$$this", monthStart}},
+					bson.M{"$lt": bson.A{"$$this", monthStart.AddDate(0, 1, 0)}},
+				}},
+			}}},
+			services.MaxStreakFreezesPerMonth,
+		}},
 	}
 
-	if len(userStats.DailyStats) == 0 {
-		return 0, 0
+	var updated models.UserStatistics
+	err = sc.DB.Collection("user_statistics").FindOneAndUpdate(ctx, filter,
+		bson.M{
+			"$addToSet": bson.M{"streak_freezes": day},
+			"$set":      bson.M{"updated_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "No streak freezes left for this month"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to freeze day"})
+		return
 	}
 
-	// Sort by date
-	dates := make([]time.Time, len(userStats.DailyStats))
-	for i, stat := range userStats.DailyStats {
-		dates[i] = stat.Date
+	c.JSON(http.StatusOK, gin.H{"streak_freezes": updated.StreakFreezes})
+}
+
+// RebuildStatistics replays the study sessions of one user, or of all users, to regenerate their
+// card mastery and statistics. It streams one progress line (NDJSON) per user as it goes.
+func (sc *StatisticsController) RebuildStatistics(c *gin.Context) {
+	var req models.RebuildStatisticsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.UserID == "") == !req.All {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either user_id or all is required"})
+		return
 	}
 
-	// Calculate current streak
-	today := time.Now().Truncate(24 * time.Hour)
-	currentStreak := 0
-	for i := len(dates) - 1; i >= 0; i-- {
-		expectedDate := today.AddDate(0, 0, -(len(dates)-1-i))
-		if dates[i].Equal(expectedDate) {
-			currentStreak++
-		} else {
-			break
+	var userIDs []primitive.ObjectID
+	if req.All {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		ids, err := sc.stats.StatisticsUserIDs(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+			return
+		}
+		userIDs = ids
+	} else {
+		userObjID, err := primitive.ObjectIDFromHex(req.UserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
 		}
+		userIDs = []primitive.ObjectID{userObjID}
 	}
 
-	// Calculate longest streak
-	longestStreak := 0
-	tempStreak := 1
-	for i := 1; i < len(dates); i++ {
-		diff := dates[i].Sub(dates[i-1]).Hours() / 24
-		if diff == 1 {
-			tempStreak++
-			if tempStreak > longestStreak {
-				longestStreak = tempStreak
-			}
+	// Rebuilding everyone outlasts the server's write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for i, userObjID := range userIDs {
+		// Stop when the client went away
+		if c.Request.Context().Err() != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), services.StatisticsRebuildTimeout)
+		report, err := sc.stats.RebuildUser(ctx, userObjID, req.DryRun)
+		cancel()
+
+		progress := models.StatisticsRebuildProgress{Done: i + 1, Total: len(userIDs), UserID: userObjID}
+		if err != nil {
+			progress.Error = err.Error()
 		} else {
-			tempStreak = 1
+			progress.Report = &report
 		}
+		encoder.Encode(progress)
+		c.Writer.Flush()
 	}
+}
 
-	if longestStreak == 0 && len(dates) > 0 {
-		longestStreak = 1
+// GetFailedStatisticsJobs lists the study sessions the statistics worker gave up on
+func (sc *StatisticsController) GetFailedStatisticsJobs(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	jobs, err := sc.stats.FailedJobs(ctx, 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch failed jobs"})
+		return
 	}
 
-	return currentStreak, longestStreak
+	c.JSON(http.StatusOK, jobs)
 }
 
-func (sc *StatisticsController) countUniqueCards(userID primitive.ObjectID) int {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// RetryFailedStatisticsJobs requeues the failed statistics jobs of one user, or of all users
+func (sc *StatisticsController) RetryFailedStatisticsJobs(c *gin.Context) {
+	var req models.RetryStatisticsJobsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	count, _ := sc.DB.Collection("card_mastery").CountDocuments(ctx, bson.M{"user_id": userID})
-	return int(count)
-}
+	var userObjID *primitive.ObjectID
+	if req.UserID != "" {
+		id, err := primitive.ObjectIDFromHex(req.UserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+			return
+		}
+		userObjID = &id
+	}
 
-func (sc *StatisticsController) getCardMasteryCounts(userID primitive.ObjectID) (int, int, int) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	mastered, _ := sc.DB.Collection("card_mastery").CountDocuments(ctx, bson.M{
-		"mastery_level": bson.M{"$gte": 80},
-	})
+	requeued, err := sc.stats.RetryFailedJobs(ctx, userObjID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to requeue jobs"})
+		return
+	}
 
-	learning, _ := sc.DB.Collection("card_mastery").CountDocuments(ctx, bson.M{
-		"mastery_level": bson.M{"$gte": 50, "$lt": 80},
-	})
+	c.JSON(http.StatusOK, gin.H{"requeued": requeued})
+}
 
-	newCards, _ := sc.DB.Collection("card_mastery").CountDocuments(ctx, bson.M{
-		"mastery_level": bson.M{"$lt": 50},
-	})
+// Helper functions
 
-	return int(mastered), int(learning), int(newCards)
+// timeseriesBucketStart returns the first day of the bucket day falls in
+func timeseriesBucketStart(day time.Time, granularity models.TimeseriesGranularity) time.Time {
+	switch granularity {
+	case models.TimeseriesWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case models.TimeseriesMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
 }
 
-func (sc *StatisticsController) updateCardMastery(userID, cardSetID primitive.ObjectID, attempts []models.CardAttempt) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// timeseriesNextBucket returns the first day of the bucket after the one starting on start
+func timeseriesNextBucket(start time.Time, granularity models.TimeseriesGranularity) time.Time {
+	switch granularity {
+	case models.TimeseriesWeek:
+		return start.AddDate(0, 0, 7)
+	case models.TimeseriesMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
 
-	for _, attempt := range attempts {
-		filter := bson.M{
-			"card_id":    attempt.CardID,
-			"user_id":    userID,
-			"cardset_id": cardSetID,
-		}
+// findSessionByKey looks up the session a user recorded with an idempotency key
+func (sc *StatisticsController) findSessionByKey(ctx context.Context, userID primitive.ObjectID, key string) (models.StudySession, bool, error) {
+	var session models.StudySession
+	err := sc.DB.Collection("study_sessions").FindOne(ctx, bson.M{
+		"user_id":         userID,
+		"idempotency_key": key,
+	}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return session, false, nil
+	}
+	if err != nil {
+		return session, false, err
+	}
+	return session, true, nil
+}
 
-		// Get existing mastery or create new
-		var mastery models.CardMastery
-		err := sc.DB.Collection("card_mastery").FindOne(ctx, filter).Decode(&mastery)
+// sessionWindow bounds a session recorded by the server: it ends at end and lasts the given
+// study time, capped at maxSessionDuration and at the time since start.
+func sessionWindow(start, end time.Time, studied time.Duration) (time.Time, int) {
+	if studied > maxSessionDuration {
+		studied = maxSessionDuration
+	}
+	if elapsed := end.Sub(start); studied > elapsed {
+		studied = elapsed
+	}
+	if studied < 0 {
+		studied = 0
+	}
+	return end.Add(-studied), int(studied.Seconds())
+}
 
-		if err == mongo.ErrNoDocuments {
-			// Create new mastery record
-			mastery = models.CardMastery{
-				CardID:    attempt.CardID,
-				UserID:    userID,
-				CardSetID: cardSetID,
-			}
-		}
+// sanitizeAttempts clamps the client supplied timings into the session window.
+// It fails when an attempt is at a card that isn't in the set.
+func sanitizeAttempts(attempts []models.CardAttempt, cards []models.CardSetCard, startTime, endTime time.Time) ([]models.CardAttempt, bool) {
+	cardIDs := make(map[string]bool, len(cards))
+	for _, card := range cards {
+		cardIDs[card.ID] = true
+	}
 
-		// Update counts
-		mastery.TimesStudied++
-		if attempt.Correct {
-			mastery.TimesCorrect++
-		} else {
-			mastery.TimesIncorrect++
+	maxTimeSpent := min(int(endTime.Sub(startTime).Seconds()), maxAttemptTimeSpent)
+	sanitized := make([]models.CardAttempt, len(attempts))
+	for i, attempt := range attempts {
+		if !cardIDs[attempt.CardID] {
+			return nil, false
 		}
 
-		// Calculate mastery level
-		if mastery.TimesStudied > 0 {
-			mastery.MasteryLevel = float64(mastery.TimesCorrect) / float64(mastery.TimesStudied) * 100
+		attempt.TimeSpent = max(0, min(attempt.TimeSpent, maxTimeSpent))
+		attempt.ConfidenceLevel = max(0, min(attempt.ConfidenceLevel, 5))
+		if attempt.AttemptedAt.IsZero() || attempt.AttemptedAt.After(endTime) {
+			attempt.AttemptedAt = endTime
+		} else if attempt.AttemptedAt.Before(startTime) {
+			attempt.AttemptedAt = startTime
 		}
-
-		mastery.LastStudied = attempt.AttemptedAt
-		mastery.LastCorrect = attempt.Correct
-
-		// Upsert
-		opts := options.Update().SetUpsert(true)
-		sc.DB.Collection("card_mastery").UpdateOne(ctx, filter, bson.M{
-			"$set": mastery,
-		}, opts)
+		sanitized[i] = attempt
 	}
+	return sanitized, true
 }
 
-func (sc *StatisticsController) getPerformanceByMode(ctx context.Context, userID primitive.ObjectID) ([]models.PerformanceByMode, error) {
+// getPerformanceByMode breaks the sessions matched by filter down by study mode.
+// Every known mode is listed, modes without sessions with zero counts.
+func (sc *StatisticsController) getPerformanceByMode(ctx context.Context, filter bson.M) ([]models.PerformanceByMode, error) {
 	pipeline := []bson.M{
-		{"$match": bson.M{"user_id": userID}},
+		{"$match": filter},
 		{"$group": bson.M{
 			"_id":           "$mode",
 			"sessions":      bson.M{"$sum": 1},
@@ -548,7 +904,7 @@ func (sc *StatisticsController) getPerformanceByMode(ctx context.Context, userID
 	}
 	defer cursor.Close(ctx)
 
-	var results []models.PerformanceByMode
+	byMode := make(map[models.StudyMode]models.PerformanceByMode)
 	for cursor.Next(ctx) {
 		var result struct {
 			Mode         models.StudyMode `bson:"_id"`
@@ -571,13 +927,22 @@ func (sc *StatisticsController) getPerformanceByMode(ctx context.Context, userID
 			avgTimePerCard = float64(result.TotalTime) / float64(result.TotalCards)
 		}
 
-		results = append(results, models.PerformanceByMode{
+		byMode[result.Mode] = models.PerformanceByMode{
 			Mode:           result.Mode,
 			Sessions:       result.Sessions,
 			TotalCards:     result.TotalCards,
 			Accuracy:       accuracy,
 			AvgTimePerCard: avgTimePerCard,
-		})
+		}
+	}
+
+	results := make([]models.PerformanceByMode, 0, len(models.StudyModes))
+	for _, mode := range models.StudyModes {
+		performance, ok := byMode[mode]
+		if !ok {
+			performance = models.PerformanceByMode{Mode: mode}
+		}
+		results = append(results, performance)
 	}
 
 	return results, nil
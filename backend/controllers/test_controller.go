@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"learn-backend/models"
+	"learn-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TestController struct {
+	db          *mongo.Database
+	testService *services.TestService
+}
+
+func NewTestController(db *mongo.Database) *TestController {
+	return &TestController{
+		db:          db,
+		testService: services.NewTestService(),
+	}
+}
+
+// CreateTest generates a new test from a card set
+func (tc *TestController) CreateTest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardSetID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cardSetObjID, err := primitive.ObjectIDFromHex(cardSetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid card set ID"})
+		return
+	}
+
+	var req models.CreateTestRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.QuestionCount == 0 {
+		req.QuestionCount = services.DefaultTestQuestionCount
+	}
+	if req.AnswerWith == "" {
+		req.AnswerWith = models.AnswerWithDefinition
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var cardSet models.CardSet
+	err = tc.db.Collection("cardsets").FindOne(ctx, withAccess(bson.M{
+		"_id":        cardSetObjID,
+		"deleted_at": nil,
+	}, userObjID, models.CollaboratorRoleViewer)).Decode(&cardSet)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Card set not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch card set"})
+		return
+	}
+
+	if len(cardSet.Cards) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Card set has no cards"})
+		return
+	}
+
+	test := models.CardTest{
+		UserID:    userObjID,
+		CardSetID: cardSetObjID,
+		Status:    models.TestStatusPending,
+		Questions: tc.testService.Generate(cardSet.Cards, req.QuestionCount, req.QuestionTypes, req.AnswerWith),
+		CreatedAt: time.Now(),
+	}
+
+	result, err := tc.db.Collection("tests").InsertOne(ctx, test)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create test"})
+		return
+	}
+
+	test.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, test)
+}
+
+// GetTest returns a test (without the expected answers)
+func (tc *TestController) GetTest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	testID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	testObjID, err := primitive.ObjectIDFromHex(testID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid test ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var test models.CardTest
+	err = tc.db.Collection("tests").FindOne(ctx, bson.M{
+		"_id":     testObjID,
+		"user_id": userObjID,
+	}).Decode(&test)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Test not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch test"})
+		return
+	}
+
+	c.JSON(http.StatusOK, test)
+}
+
+// SubmitTest grades the answers of a test and records the study session
+func (tc *TestController) SubmitTest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	testID := c.Param("id")
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	testObjID, err := primitive.ObjectIDFromHex(testID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid test ID"})
+		return
+	}
+
+	var req models.SubmitTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	testsCollection := tc.db.Collection("tests")
+
+	var test models.CardTest
+	err = testsCollection.FindOne(ctx, bson.M{
+		"_id":     testObjID,
+		"user_id": userObjID,
+	}).Decode(&test)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Test not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch test"})
+		return
+	}
+
+	if test.Status == models.TestStatusSubmitted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Test has already been submitted"})
+		return
+	}
+
+	submittedAt := time.Now()
+	results := tc.testService.Grade(test.Questions, req.Answers)
+
+	correct := 0
+	for _, result := range results {
+		if result.Correct {
+			correct++
+		}
+	}
+	total := len(results)
+	score := 0.0
+	if total > 0 {
+		score = float64(correct) / float64(total) * 100
+	}
+
+	// Tests don't time their answers; count at most the time allowed per question
+	startTime, duration := sessionWindow(test.CreatedAt, submittedAt, time.Duration(len(test.Questions)*maxAttemptTimeSpent)*time.Second)
+
+	session := models.StudySession{
+		UserID:     userObjID,
+		CardSetID:  test.CardSetID,
+		Mode:       models.StudyModeTest,
+		TestID:     &test.ID,
+		StartTime:  startTime,
+		EndTime:    submittedAt,
+		Duration:   duration,
+		TotalCards: total,
+		Correct:    correct,
+		Incorrect:  total - correct,
+		Accuracy:   score,
+		Attempts:   tc.testService.ToAttempts(results, submittedAt),
+		StatsJob:   models.NewStatsJob(),
+		CreatedAt:  submittedAt,
+	}
+
+	// Record the study session first; its unique test id keeps a replayed request from recording the test twice
+	insertResult, err := tc.db.Collection("study_sessions").InsertOne(ctx, session)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// An earlier submission was recorded; finish marking the test submitted if it failed
+			var existing models.StudySession
+			if err := tc.db.Collection("study_sessions").FindOne(ctx, bson.M{"test_id": testObjID}).Decode(&existing); err == nil {
+				tc.markSubmitted(ctx, testObjID, existing)
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "Test has already been submitted"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record study session"})
+		return
+	}
+	session.ID = insertResult.InsertedID.(primitive.ObjectID)
+
+	if err := tc.markSubmitted(ctx, testObjID, session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit test"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SubmitTestResponse{
+		Score:        score,
+		Correct:      correct,
+		Total:        total,
+		Results:      results,
+		StudySession: session,
+	})
+}
+
+// markSubmitted marks a pending test submitted with its recorded study session
+func (tc *TestController) markSubmitted(ctx context.Context, testID primitive.ObjectID, session models.StudySession) error {
+	_, err := tc.db.Collection("tests").UpdateOne(ctx, bson.M{
+		"_id":    testID,
+		"status": models.TestStatusPending,
+	}, bson.M{"$set": bson.M{
+		"status":           models.TestStatusSubmitted,
+		"score":            session.Accuracy,
+		"submitted_at":     session.EndTime,
+		"study_session_id": session.ID,
+	}})
+	return err
+}
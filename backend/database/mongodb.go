@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -49,6 +50,12 @@ func CreateIndexes(db *mongo.Database) error {
 
 	// CardSets collection indexes
 	cardSetsCollection := db.Collection("cardsets")
+
+	// The search index gained tags under a new name; a collection can only have one text index
+	if _, err := cardSetsCollection.Indexes().DropOne(ctx, "cardsets_text"); err != nil && !isNotFound(err) {
+		return err
+	}
+
 	_, err = cardSetsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{
 			Keys: bson.D{{Key: "user_id", Value: 1}},
@@ -56,6 +63,79 @@ func CreateIndexes(db *mongo.Database) error {
 		{
 			Keys: bson.D{{Key: "created_at", Value: -1}},
 		},
+		{
+			Keys: bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		// Owner listing filters
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "folder_id", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "tags", Value: 1},
+			},
+		},
+		// Sets shared with a collaborator
+		{
+			Keys: bson.D{{Key: "collaborators.user_id", Value: 1}},
+		},
+		// A student has one copy of each class assignment
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "class_assignment_id", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"class_assignment_id": bson.M{"$exists": true}}),
+		},
+		// Global library listing, one index per sort order
+		{
+			Keys: bson.D{
+				{Key: "is_public", Value: 1},
+				{Key: "download_count", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "is_public", Value: 1},
+				{Key: "created_at", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "is_public", Value: 1},
+				{Key: "rating_average", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		// Global library search. Card sets are multilingual, so no stemming, and the
+		// language field (codes like "vi" that text search rejects) must not be the override.
+		{
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "tags", Value: "text"},
+				{Key: "cards.terminology", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("cardsets_text_v2").
+				SetDefaultLanguage("none").
+				SetLanguageOverride("text_language").
+				SetWeights(bson.D{
+					{Key: "title", Value: 10},
+					{Key: "tags", Value: 5},
+					{Key: "description", Value: 3},
+					{Key: "cards.terminology", Value: 1},
+				}),
+		},
 	})
 	if err != nil {
 		return err
@@ -83,6 +163,50 @@ func CreateIndexes(db *mongo.Database) error {
 				{Key: "created_at", Value: -1},
 			},
 		},
+		{
+			// Recalculation window of a user's sessions
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "start_time", Value: 1},
+			},
+		},
+		{
+			// Overlap check of recorded sessions
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "end_time", Value: 1},
+			},
+		},
+		{
+			// Client retries of a session share its idempotency key
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "idempotency_key", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$exists": true}}),
+		},
+		{
+			// A learn session is recorded once
+			Keys: bson.D{{Key: "learn_session_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"learn_session_id": bson.M{"$exists": true}}),
+		},
+		{
+			// A test is recorded once
+			Keys: bson.D{{Key: "test_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"test_id": bson.M{"$exists": true}}),
+		},
+		{
+			// Queue of sessions waiting for the statistics worker
+			Keys: bson.D{{Key: "stats_job.run_at", Value: 1}},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"stats_job": bson.M{"$exists": true}}),
+		},
 	})
 	if err != nil {
 		return err
@@ -95,6 +219,12 @@ func CreateIndexes(db *mongo.Database) error {
 			Keys:    bson.D{{Key: "user_id", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
+		{
+			// Queue of recalculations waiting for the statistics worker
+			Keys: bson.D{{Key: "recalculate_job.run_at", Value: 1}},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"recalculate_job": bson.M{"$exists": true}}),
+		},
 	})
 	if err != nil {
 		return err
@@ -112,6 +242,13 @@ func CreateIndexes(db *mongo.Database) error {
 		{
 			Keys: bson.D{{Key: "mastery_level", Value: 1}},
 		},
+		{
+			// Compound index for the daily review queue
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "due_at", Value: 1},
+			},
+		},
 		{
 			// Compound unique index to ensure one mastery record per card per user
 			Keys: bson.D{
@@ -126,5 +263,162 @@ func CreateIndexes(db *mongo.Database) error {
 		return err
 	}
 
+	// LearnSessions collection indexes
+	learnSessionsCollection := db.Collection("learn_sessions")
+	_, err = learnSessionsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "cardset_id", Value: 1},
+				{Key: "status", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tests collection indexes
+	testsCollection := db.Collection("tests")
+	_, err = testsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// CardSetShares collection indexes
+	cardSetSharesCollection := db.Collection("cardset_shares")
+	_, err = cardSetSharesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "cardset_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Folders collection indexes
+	foldersCollection := db.Collection("folders")
+	_, err = foldersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// Sibling folders have distinct names
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "parent_id", Value: 1},
+				{Key: "name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// CardSetRatings collection indexes
+	cardSetRatingsCollection := db.Collection("cardset_ratings")
+	_, err = cardSetRatingsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// One rating per user and card set
+			Keys: bson.D{
+				{Key: "cardset_id", Value: 1},
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "cardset_id", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// CardSetReports collection indexes
+	cardSetReportsCollection := db.Collection("cardset_reports")
+	_, err = cardSetReportsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// One report per user and card set
+			Keys: bson.D{
+				{Key: "cardset_id", Value: 1},
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// CardSetRevisions collection indexes
+	cardSetRevisionsCollection := db.Collection("cardset_revisions")
+	_, err = cardSetRevisionsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "cardset_id", Value: 1},
+				{Key: "revision", Value: -1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Classes collection indexes
+	classesCollection := db.Collection("classes")
+	_, err = classesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "join_code", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "teacher_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "students.user_id", Value: 1}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// ClassAssignments collection indexes
+	classAssignmentsCollection := db.Collection("class_assignments")
+	_, err = classAssignmentsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// A card set is assigned to a class once
+			Keys: bson.D{
+				{Key: "class_id", Value: 1},
+				{Key: "cardset_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// isNotFound reports whether err is a missing collection or index error
+func isNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27) // NamespaceNotFound, IndexNotFound
+}
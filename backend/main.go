@@ -8,9 +8,11 @@ import (
 	"syscall"
 	"time"
 
+	"learn-backend/cmd"
 	"learn-backend/config"
 	"learn-backend/database"
 	"learn-backend/routes"
+	"learn-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -46,6 +48,25 @@ func main() {
 		log.Fatal("Failed to create indexes:", err)
 	}
 
+	// Run a maintenance command instead of the server, e.g. `stats rebuild --all`
+	if len(os.Args) > 1 {
+		if err := cmd.Run(db, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Purge card sets that stayed in the trash past the retention period
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	services.NewTrashPurgeService(db, cfg.TrashRetentionDays).Start(purgeCtx)
+
+	// Apply recorded study sessions to the statistics
+	statsCtx, stopStats := context.WithCancel(context.Background())
+	defer stopStats()
+	statsWorker := services.NewStatisticsService(db)
+	statsWorker.Start(statsCtx)
+
 	// Setup router
 	router := gin.Default()
 	routes.SetupRoutes(router, db, cfg)
@@ -59,6 +80,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopPurge()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -67,5 +89,9 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	// Let the statistics worker finish its job; unfinished jobs stay queued
+	stopStats()
+	statsWorker.Wait()
+
 	log.Println("Server exited")
 }
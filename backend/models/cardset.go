@@ -32,25 +32,92 @@ type StudyProgress struct {
 }
 
 type CardSet struct {
-	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Title          string              `json:"title" bson:"title" binding:"required,max=200"`
+	Description    string              `json:"description" bson:"description" binding:"max=1000"`
+	Language       string              `json:"language" bson:"language" binding:"max=10"`
+	Cards          []CardSetCard       `json:"cards" bson:"cards"`
+	Tags           []string            `json:"tags" bson:"tags,omitempty"`
+	FolderID       *primitive.ObjectID `json:"folder_id,omitempty" bson:"folder_id,omitempty"`
+	Collaborators  []Collaborator      `json:"collaborators,omitempty" bson:"collaborators,omitempty"`
+	Progress       StudyProgress       `json:"progress" bson:"progress"`
+	IsPublic       bool                `json:"is_public" bson:"is_public"`
+	DownloadCount  int                 `json:"download_count" bson:"download_count"`
+	RatingAverage  float64             `json:"rating_average" bson:"rating_average"`
+	RatingCount    int                 `json:"rating_count" bson:"rating_count"`
+	RatingSum      int                 `json:"-" bson:"rating_sum"`
+	ReportCount    int                 `json:"-" bson:"report_count"`
+	Hidden         bool                `json:"hidden,omitempty" bson:"hidden,omitempty"` // hidden from the global library after too many reports
+	ForkedFrom     *ForkOrigin         `json:"forked_from,omitempty" bson:"forked_from,omitempty"`
+	AssignmentID   *primitive.ObjectID `json:"class_assignment_id,omitempty" bson:"class_assignment_id,omitempty"` // set on a student's copy of a class assignment
+	PhoneticStatus string              `json:"phonetic_status" bson:"phonetic_status"`
+	Version        int64               `json:"version" bson:"version"`                           // incremented on every write, exposed as ETag
+	DeletedAt      *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // set while the card set is in the trash
+	WasPublic      bool                `json:"-" bson:"was_public,omitempty"`                    // is_public before the set was trashed
+	PurgingAt      *time.Time          `json:"-" bson:"purging_at,omitempty"`                    // set once the purger claimed the set; it can no longer be restored
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// LibrarySort constants for the global library listing
+const (
+	LibrarySortPopular  = "popular"
+	LibrarySortNewest   = "newest"
+	LibrarySortTrending = "trending"
+	LibrarySortRating   = "rating"
+)
+
+// CardSetSummary is the global library view of a card set, without its cards
+type CardSetSummary struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id"`
 	UserID         primitive.ObjectID `json:"user_id" bson:"user_id"`
-	Title          string             `json:"title" bson:"title" binding:"required,max=200"`
-	Description    string             `json:"description" bson:"description" binding:"max=1000"`
-	Language       string             `json:"language" bson:"language" binding:"max=10"`
-	Cards          []CardSetCard      `json:"cards" bson:"cards"`
-	Progress       StudyProgress      `json:"progress" bson:"progress"`
-	IsPublic       bool               `json:"is_public" bson:"is_public"`
+	Title          string             `json:"title" bson:"title"`
+	Description    string             `json:"description" bson:"description"`
+	Language       string             `json:"language" bson:"language"`
+	Tags           []string           `json:"tags" bson:"tags"`
+	CardCount      int                `json:"card_count" bson:"card_count"`
+	DownloadCount  int                `json:"download_count" bson:"download_count"`
+	RatingAverage  float64            `json:"rating_average" bson:"rating_average"`
+	RatingCount    int                `json:"rating_count" bson:"rating_count"`
+	PhoneticStatus string             `json:"phonetic_status" bson:"phonetic_status"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+	SortValue      float64            `json:"-" bson:"sort_value"`
+}
+
+// PublicCardSetDetail is a public card set as shown before importing it, with the first cards only
+type PublicCardSetDetail struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id"`
+	Title          string             `json:"title" bson:"title"`
+	Description    string             `json:"description" bson:"description"`
+	Language       string             `json:"language" bson:"language"`
+	Tags           []string           `json:"tags" bson:"tags"`
+	Owner          PublicProfile      `json:"owner" bson:"-"`
+	UserID         primitive.ObjectID `json:"-" bson:"user_id"`
+	CardCount      int                `json:"card_count" bson:"card_count"`
 	DownloadCount  int                `json:"download_count" bson:"download_count"`
+	RatingAverage  float64            `json:"rating_average" bson:"rating_average"`
+	RatingCount    int                `json:"rating_count" bson:"rating_count"`
 	PhoneticStatus string             `json:"phonetic_status" bson:"phonetic_status"`
+	Cards          []CardSetCard      `json:"cards" bson:"cards"`
 	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// CardSetPage is one page of the global library
+type CardSetPage struct {
+	Items      []CardSetSummary `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
 type CreateCardSetRequest struct {
 	Title       string        `json:"title" binding:"required,max=200"`
 	Description string        `json:"description" binding:"max=1000"`
 	Language    string        `json:"language" binding:"max=10"`
 	Cards       []CardSetCard `json:"cards" binding:"required,min=1"`
+	Tags        []string      `json:"tags" binding:"max=20,dive,max=50"`
+	FolderID    string        `json:"folder_id"`
 }
 
 type UpdateCardSetRequest struct {
@@ -58,5 +125,61 @@ type UpdateCardSetRequest struct {
 	Description    string        `json:"description" binding:"max=1000"`
 	Language       string        `json:"language" binding:"max=10"`
 	Cards          []CardSetCard `json:"cards"`
+	Tags           []string      `json:"tags" binding:"max=20,dive,max=50"` // replaces the tags when given, [] clears them
 	PhoneticStatus string        `json:"phonetic_status" binding:"max=20"`
 }
+
+type UpdateCardRequest struct {
+	Terminology  *string `json:"terminology" binding:"omitempty,min=1,max=500"`
+	Define       *string `json:"define" binding:"omitempty,min=1,max=2000"`
+	Example      *string `json:"example" binding:"omitempty,max=1000"`
+	ImageURL     *string `json:"image_url" binding:"omitempty,max=500"`
+	PartOfSpeech *string `json:"part_of_speech" binding:"omitempty,max=50"`
+	Phonetic     *string `json:"phonetic" binding:"omitempty,max=100"`
+}
+
+type AddCardsRequest struct {
+	Cards    []CardSetCard `json:"cards" binding:"required,min=1,max=1000,dive"`
+	Position *int          `json:"position" binding:"omitempty,min=0"` // insert position, appended when omitted
+}
+
+type ReorderCardsRequest struct {
+	CardIDs []string `json:"card_ids" binding:"required,min=1"`
+}
+
+// CardResponse is a changed card with the card set version after the change
+type CardResponse struct {
+	CardSetCard
+	Version int64 `json:"version"`
+}
+
+type CheckAnswerRequest struct {
+	Answer     string `json:"answer" binding:"max=2000"`
+	AnswerWith string `json:"answer_with" binding:"omitempty,oneof=definition term"`
+}
+
+// ImportCardSetRequest holds the multipart form fields of a CSV/TSV or Anki import
+type ImportCardSetRequest struct {
+	Format        string `form:"format" binding:"omitempty,oneof=csv tsv apkg"` // detected from the file name when omitted; large Anki packages need ?format=apkg
+	Title         string `form:"title" binding:"required,max=200"`
+	Description   string `form:"description" binding:"max=1000"`
+	Language      string `form:"language" binding:"max=10"`
+	Text          string `form:"text"`           // pasted text, used when no file is uploaded
+	TermDelimiter string `form:"term_delimiter"` // tab (default), comma, semicolon, dash or a literal string
+	RowDelimiter  string `form:"row_delimiter"`  // newline (default), semicolon or a literal string
+	Columns       string `form:"columns"`        // comma separated card fields, defaults to terminology,define
+	HasHeader     bool   `form:"has_header"`
+	FieldMapping  string `form:"field_mapping"` // apkg only: JSON object of note field name to card field
+}
+
+// ImportRowError reports why one row of an import was skipped
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type ImportCardSetResponse struct {
+	CardSet  CardSet          `json:"cardset"`
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors"`
+}
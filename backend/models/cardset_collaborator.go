@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CollaboratorRole constants, from least to most privileged.
+// Viewers can read a set, editors can change its cards, admins can also manage collaborators and delete it.
+const (
+	CollaboratorRoleViewer = "viewer"
+	CollaboratorRoleEditor = "editor"
+	CollaboratorRoleAdmin  = "admin"
+)
+
+// Collaborator gives another user access to a card set. Access starts once the invitation is accepted.
+type Collaborator struct {
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Role       string             `json:"role" bson:"role"`
+	InvitedBy  primitive.ObjectID `json:"invited_by" bson:"invited_by"`
+	InvitedAt  time.Time          `json:"invited_at" bson:"invited_at"`
+	AcceptedAt *time.Time         `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+}
+
+// InviteCollaboratorRequest invites a user by email or username
+type InviteCollaboratorRequest struct {
+	User string `json:"user" binding:"required,max=100"`
+	Role string `json:"role" binding:"required,oneof=viewer editor admin"`
+}
+
+type UpdateCollaboratorRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor admin"`
+}
+
+// CollaboratorView is a collaborator together with their public profile
+type CollaboratorView struct {
+	Collaborator
+	User PublicProfile `json:"user"`
+}
+
+// CardSetInvitation is a pending collaboration invitation of the current user
+type CardSetInvitation struct {
+	CardSetID primitive.ObjectID `json:"cardset_id"`
+	Title     string             `json:"title"`
+	Role      string             `json:"role"`
+	InvitedBy PublicProfile      `json:"invited_by"`
+	InvitedAt time.Time          `json:"invited_at"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ForkOrigin records which card set a copy was made from, and the source
+// revision it was last synced with
+type ForkOrigin struct {
+	CardSetID primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
+	Revision  int64              `json:"revision" bson:"revision"` // source version at the fork or last sync
+	SyncedAt  time.Time          `json:"synced_at" bson:"synced_at"`
+	Cards     []ForkedCard       `json:"-" bson:"cards"`
+}
+
+// ForkedCard maps a card of the fork to its source card
+type ForkedCard struct {
+	ID       string `bson:"id"`        // card id in the fork
+	SourceID string `bson:"source_id"` // card id in the source set
+	Hash     string `bson:"hash"`      // content hash of the source card when last synced
+}
+
+// UpstreamCardChange pairs a card of the fork with its source card.
+// Upstream is empty when the source card was removed.
+type UpstreamCardChange struct {
+	Local    CardSetCard  `json:"local"`
+	Upstream *CardSetCard `json:"upstream,omitempty"`
+}
+
+// UpstreamDiff lists the source changes since the fork was last synced.
+// Conflicts are cards edited on both sides; syncing keeps the local version.
+type UpstreamDiff struct {
+	UpstreamID       primitive.ObjectID   `json:"upstream_id"`
+	BaseRevision     int64                `json:"base_revision"`
+	UpstreamRevision int64                `json:"upstream_revision"`
+	Added            []CardSetCard        `json:"added"`
+	Changed          []UpstreamCardChange `json:"changed"`
+	Removed          []CardSetCard        `json:"removed"`
+	Conflicts        []UpstreamCardChange `json:"conflicts"`
+}
+
+type SyncUpstreamResponse struct {
+	CardSet CardSet      `json:"cardset"`
+	Diff    UpstreamDiff `json:"diff"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CardSetRating is one user's star rating of a public card set, optionally with a review comment
+type CardSetRating struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CardSetID primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Stars     int                `json:"stars" bson:"stars"`
+	Comment   string             `json:"comment,omitempty" bson:"comment,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type RateCardSetRequest struct {
+	Stars   int    `json:"stars" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=1000"`
+}
+
+// CardSetReview is a rating together with the public profile of its author
+type CardSetReview struct {
+	CardSetRating `bson:",inline"`
+	User          PublicProfile `json:"user" bson:"user"`
+}
+
+// CardSetReviewPage is one page of the reviews of a card set
+type CardSetReviewPage struct {
+	Items      []CardSetReview `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// ReportReason constants
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonCopyright     = "copyright"
+	ReportReasonIncorrect     = "incorrect"
+	ReportReasonOther         = "other"
+)
+
+// CardSetReport flags a public card set for moderation. Each user can report a set once.
+type CardSetReport struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CardSetID primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Reason    string             `json:"reason" bson:"reason"`
+	Details   string             `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type ReportCardSetRequest struct {
+	Reason  string `json:"reason" binding:"required,oneof=spam inappropriate copyright incorrect other"`
+	Details string `json:"details" binding:"max=1000"`
+}
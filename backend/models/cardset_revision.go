@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CardSetRevision is a snapshot of a card set taken before it was overwritten
+type CardSetRevision struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CardSetID   primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Revision    int64              `json:"revision" bson:"revision"` // card set version of the snapshot
+	Title       string             `json:"title" bson:"title"`
+	Description string             `json:"description" bson:"description"`
+	Language    string             `json:"language" bson:"language"`
+	Cards       []CardSetCard      `json:"cards,omitempty" bson:"cards"`
+	CardCount   int                `json:"card_count" bson:"card_count"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
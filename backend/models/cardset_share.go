@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CardSetShare is a revocable link that lets anyone holding the token copy a card set
+type CardSetShare struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Token        string             `json:"token" bson:"token"`
+	CardSetID    primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
+	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
+	PasswordHash string             `json:"-" bson:"password_hash,omitempty"`
+	HasPassword  bool               `json:"has_password" bson:"has_password"`
+	ExpiresAt    *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	MaxUses      int                `json:"max_uses,omitempty" bson:"max_uses,omitempty"` // 0 means unlimited
+	UseCount     int                `json:"use_count" bson:"use_count"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type CreateShareRequest struct {
+	ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,min=1,max=8760"`
+	MaxUses        int    `json:"max_uses" binding:"omitempty,min=1"`
+	Password       string `json:"password" binding:"max=100"`
+}
+
+type ImportShareRequest struct {
+	Password string `json:"password"`
+}
+
+// SharePreview is what an unauthenticated visitor of a share link sees.
+// Cards are left out until the password, if any, is given.
+type SharePreview struct {
+	Token            string        `json:"token"`
+	Title            string        `json:"title"`
+	Description      string        `json:"description"`
+	Language         string        `json:"language"`
+	CardCount        int           `json:"card_count"`
+	Cards            []CardSetCard `json:"cards,omitempty"`
+	RequiresPassword bool          `json:"requires_password"`
+	ExpiresAt        *time.Time    `json:"expires_at,omitempty"`
+}
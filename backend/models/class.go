@@ -0,0 +1,123 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ClassJoinCodeLength is the length of the codes students join classes with
+const ClassJoinCodeLength = 8
+
+// Class is a group of students managed by a teacher.
+// JoinCode and Students are only shown to the teacher.
+type Class struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TeacherID   primitive.ObjectID `json:"teacher_id" bson:"teacher_id"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description" bson:"description"`
+	JoinCode    string             `json:"join_code,omitempty" bson:"join_code"`
+	Students    []ClassStudent     `json:"students,omitempty" bson:"students"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type ClassStudent struct {
+	UserID   primitive.ObjectID `json:"user_id" bson:"user_id"`
+	JoinedAt time.Time          `json:"joined_at" bson:"joined_at"`
+}
+
+// ClassSummary is a class in the user's class list
+type ClassSummary struct {
+	Class
+	Teacher      PublicProfile `json:"teacher"`
+	StudentCount int           `json:"student_count"`
+	IsTeacher    bool          `json:"is_teacher"`
+}
+
+type CreateClassRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+}
+
+type UpdateClassRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=1000"`
+}
+
+type JoinClassRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ClassAssignment is a card set the teacher assigned to a class.
+// Students study their own copy of the set, which keeps the assigned set as its fork origin
+// and the assignment as its class_assignment_id.
+type ClassAssignment struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ClassID    primitive.ObjectID `json:"class_id" bson:"class_id"`
+	CardSetID  primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
+	AssignedBy primitive.ObjectID `json:"assigned_by" bson:"assigned_by"`
+	DueAt      *time.Time         `json:"due_at,omitempty" bson:"due_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// ClassAssignmentView is an assignment with its card set details.
+// StudentCardSetID is the current student's copy once they started the assignment.
+type ClassAssignmentView struct {
+	ClassAssignment  `bson:",inline"`
+	Title            string              `json:"title" bson:"title"`
+	CardCount        int                 `json:"card_count" bson:"card_count"`
+	StudentCardSetID *primitive.ObjectID `json:"student_cardset_id,omitempty" bson:"-"`
+}
+
+type CreateAssignmentRequest struct {
+	CardSetID string     `json:"cardset_id" binding:"required"`
+	DueAt     *time.Time `json:"due_at"`
+}
+
+// UpdateAssignmentRequest replaces the due date; null removes it
+type UpdateAssignmentRequest struct {
+	DueAt *time.Time `json:"due_at"`
+}
+
+// AssignmentProgress is one student's progress on one assignment
+type AssignmentProgress struct {
+	AssignmentID  primitive.ObjectID `json:"assignment_id"`
+	Started       bool               `json:"started"`
+	Sessions      int                `json:"sessions"`
+	StudyTime     int                `json:"study_time"` // in seconds
+	Accuracy      float64            `json:"accuracy"`
+	CardsStudied  int                `json:"cards_studied"`
+	CardsMastered int                `json:"cards_mastered"`
+	TotalCards    int                `json:"total_cards"`
+	Progress      float64            `json:"progress"` // mastered cards, 0-100%
+	LastStudiedAt *time.Time         `json:"last_studied_at,omitempty"`
+	Overdue       bool               `json:"overdue"`
+}
+
+// StudentReport sums up a student's progress over all assignments of a class
+type StudentReport struct {
+	Student       PublicProfile        `json:"student"`
+	JoinedAt      time.Time            `json:"joined_at"`
+	Sessions      int                  `json:"sessions"`
+	StudyTime     int                  `json:"study_time"` // in seconds
+	Accuracy      float64              `json:"accuracy"`
+	CardsMastered int                  `json:"cards_mastered"`
+	Assignments   []AssignmentProgress `json:"assignments"`
+}
+
+// AssignmentReport sums up the progress of all students on an assignment
+type AssignmentReport struct {
+	ClassAssignmentView
+	StudentsStarted   int     `json:"students_started"`
+	StudentsCompleted int     `json:"students_completed"`
+	AverageProgress   float64 `json:"average_progress"`
+	AverageAccuracy   float64 `json:"average_accuracy"`
+}
+
+// ClassReport is the teacher's overview of a class
+type ClassReport struct {
+	ClassID     primitive.ObjectID `json:"class_id"`
+	Students    []StudentReport    `json:"students"`
+	Assignments []AssignmentReport `json:"assignments"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxFolderDepth limits how deeply folders can be nested
+const MaxFolderDepth = 10
+
+// Folder groups a user's card sets. Folders nest through ParentID; root folders have none.
+type Folder struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID       primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	ParentID     *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Name         string              `json:"name" bson:"name"`
+	CardSetCount int                 `json:"cardset_count" bson:"-"`
+	CreatedAt    time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateFolderRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	ParentID string `json:"parent_id"` // root when empty
+}
+
+// UpdateFolderRequest renames and/or moves a folder. An empty parent_id moves it to the root.
+type UpdateFolderRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=100"`
+	ParentID *string `json:"parent_id"`
+}
+
+// MoveCardSetsRequest moves card sets into a folder, or out of all folders when folder_id is empty
+type MoveCardSetsRequest struct {
+	CardSetIDs []string `json:"cardset_ids" binding:"required,min=1,max=500"`
+	FolderID   string   `json:"folder_id"`
+}
+
+// TagCount is a tag with the number of the user's card sets carrying it
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LearnSessionStatus constants
+const (
+	LearnSessionStatusActive    = "active"
+	LearnSessionStatusCompleted = "completed"
+)
+
+// QuestionType represents the kind of question asked in learn mode and tests
+type QuestionType string
+
+const (
+	QuestionTypeMultipleChoice QuestionType = "multiple_choice"
+	QuestionTypeTrueFalse      QuestionType = "true_false"
+	QuestionTypeWritten        QuestionType = "written"
+	QuestionTypeMatching       QuestionType = "matching"
+)
+
+// AnswerWith constants (which side of the card the learner has to produce)
+const (
+	AnswerWithDefinition = "definition"
+	AnswerWithTerm       = "term"
+	AnswerWithBoth       = "both" // tests only: mixed per question
+)
+
+// Learn stages a card goes through before it is mastered
+const (
+	LearnStageNew        = 0 // asked with multiple choice / true-false
+	LearnStageRecognized = 1 // asked with a written question
+	LearnStageMastered   = 2
+)
+
+// LearnCardState tracks the progress of one card inside a learn session
+type LearnCardState struct {
+	CardID    string `json:"card_id" bson:"card_id"`
+	Stage     int    `json:"stage" bson:"stage"`
+	Correct   int    `json:"correct" bson:"correct"`
+	Incorrect int    `json:"incorrect" bson:"incorrect"`
+	Answered  bool   `json:"answered" bson:"answered"` // answered in the current round
+}
+
+// LearnQuestion is a question served to the learner. The expected answer never leaves the server.
+type LearnQuestion struct {
+	ID        string       `json:"id" bson:"id"`
+	CardID    string       `json:"card_id" bson:"card_id"`
+	Type      QuestionType `json:"type" bson:"type"`
+	Prompt    string       `json:"prompt" bson:"prompt"`
+	Statement string       `json:"statement,omitempty" bson:"statement,omitempty"` // candidate answer for true/false
+	Options   []string     `json:"options,omitempty" bson:"options,omitempty"`
+	Answer    string       `json:"-" bson:"answer"`
+	AskedAt   time.Time    `json:"asked_at" bson:"asked_at"`
+}
+
+// LearnSession represents a stateful, server-graded learn mode session
+type LearnSession struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID          primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	CardSetID       primitive.ObjectID  `json:"cardset_id" bson:"cardset_id"`
+	Status          string              `json:"status" bson:"status"`
+	AnswerWith      string              `json:"answer_with" bson:"answer_with"`
+	RoundSize       int                 `json:"round_size" bson:"round_size"`
+	Round           int                 `json:"round" bson:"round"`
+	RoundCardIDs    []string            `json:"round_card_ids" bson:"round_card_ids"`
+	Cards           []LearnCardState    `json:"cards" bson:"cards"`
+	CurrentQuestion *LearnQuestion      `json:"current_question,omitempty" bson:"current_question,omitempty"`
+	Attempts        []CardAttempt       `json:"attempts" bson:"attempts"`
+	StudySessionID  *primitive.ObjectID `json:"study_session_id,omitempty" bson:"study_session_id,omitempty"`
+	StartTime       time.Time           `json:"start_time" bson:"start_time"`
+	EndTime         *time.Time          `json:"end_time,omitempty" bson:"end_time,omitempty"`
+	CreatedAt       time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateLearnSessionRequest struct {
+	AnswerWith string `json:"answer_with" binding:"omitempty,oneof=definition term"`
+	RoundSize  int    `json:"round_size" binding:"omitempty,min=1,max=50"`
+}
+
+type LearnAnswerRequest struct {
+	QuestionID string `json:"question_id" binding:"required"`
+	Answer     string `json:"answer" binding:"max=2000"`
+	TimeSpent  int    `json:"time_spent" binding:"min=0"`
+}
+
+// LearnAnswerResponse is returned after grading an answer
+type LearnAnswerResponse struct {
+	Correct       bool          `json:"correct"`
+	CorrectAnswer string        `json:"correct_answer"`
+	Stage         int           `json:"stage"`
+	Session       *LearnSession `json:"session"`
+}
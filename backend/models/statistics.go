@@ -13,8 +13,33 @@ const (
 	StudyModeFlashcard StudyMode = "flashcard"
 	StudyModeTest      StudyMode = "test"
 	StudyModeWrite     StudyMode = "write"
+	StudyModeLearn     StudyMode = "learn"
+	StudyModeListen    StudyMode = "listen"
+	StudyModeMatch     StudyMode = "match"
+	StudyModeSpell     StudyMode = "spell"
 )
 
+// StudyModes lists every study mode, in the order statistics report them
+var StudyModes = []StudyMode{
+	StudyModeFlashcard,
+	StudyModeLearn,
+	StudyModeWrite,
+	StudyModeSpell,
+	StudyModeListen,
+	StudyModeMatch,
+	StudyModeTest,
+}
+
+// IsValid reports whether m is a known study mode
+func (m StudyMode) IsValid() bool {
+	for _, mode := range StudyModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
 // CardAttempt represents a single attempt at studying a card
 type CardAttempt struct {
 	CardID         string    `json:"card_id" bson:"card_id"`
@@ -27,19 +52,37 @@ type CardAttempt struct {
 
 // StudySession represents a complete study session
 type StudySession struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
-	CardSetID   primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
-	Mode        StudyMode          `json:"mode" bson:"mode"`
-	StartTime   time.Time          `json:"start_time" bson:"start_time"`
-	EndTime     time.Time          `json:"end_time" bson:"end_time"`
-	Duration    int                `json:"duration" bson:"duration"`        // in seconds
-	TotalCards  int                `json:"total_cards" bson:"total_cards"`  // cards in session
-	Correct     int                `json:"correct" bson:"correct"`          // correct answers
-	Incorrect   int                `json:"incorrect" bson:"incorrect"`      // incorrect answers
-	Accuracy    float64            `json:"accuracy" bson:"accuracy"`        // percentage
-	Attempts    []CardAttempt      `json:"attempts" bson:"attempts"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	CardSetID      primitive.ObjectID  `json:"cardset_id" bson:"cardset_id"`
+	Mode           StudyMode           `json:"mode" bson:"mode"`
+	IdempotencyKey string              `json:"-" bson:"idempotency_key,omitempty"`                           // client key, set on sessions recorded by the client
+	LearnSessionID *primitive.ObjectID `json:"learn_session_id,omitempty" bson:"learn_session_id,omitempty"` // set on sessions of a finished learn session
+	TestID         *primitive.ObjectID `json:"test_id,omitempty" bson:"test_id,omitempty"`                   // set on sessions of a submitted test
+	StartTime      time.Time           `json:"start_time" bson:"start_time"`
+	EndTime        time.Time           `json:"end_time" bson:"end_time"`
+	Duration       int                 `json:"duration" bson:"duration"`       // in seconds
+	TotalCards     int                 `json:"total_cards" bson:"total_cards"` // cards in session
+	Correct        int                 `json:"correct" bson:"correct"`         // correct answers
+	Incorrect      int                 `json:"incorrect" bson:"incorrect"`     // incorrect answers
+	Accuracy       float64             `json:"accuracy" bson:"accuracy"`       // percentage
+	Attempts       []CardAttempt       `json:"attempts" bson:"attempts"`
+	StatsJob       *StatsJob           `json:"-" bson:"stats_job,omitempty"` // set until the session is applied to the statistics
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// StatsJob queues a session for the statistics worker; it is removed once the session is applied.
+// A job that used up its attempts stays as a failed job until an admin retries it.
+type StatsJob struct {
+	Attempts  int        `bson:"attempts"`
+	RunAt     time.Time  `bson:"run_at"` // due time, pushed out while a worker holds the job
+	LastError string     `bson:"last_error,omitempty"`
+	FailedAt  *time.Time `bson:"failed_at,omitempty"` // set when the worker gave up on the job
+}
+
+// NewStatsJob queues a new session for the statistics worker right away
+func NewStatsJob() *StatsJob {
+	return &StatsJob{RunAt: time.Now()}
 }
 
 // DailyStats represents daily aggregated statistics
@@ -53,36 +96,181 @@ type DailyStats struct {
 
 // CardMastery represents mastery level for a specific card
 type CardMastery struct {
-	CardID         string             `json:"card_id" bson:"card_id"`
-	UserID         primitive.ObjectID `json:"user_id" bson:"user_id"`
-	CardSetID      primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
-	TimesStudied   int                `json:"times_studied" bson:"times_studied"`
-	TimesCorrect   int                `json:"times_correct" bson:"times_correct"`
-	TimesIncorrect int                `json:"times_incorrect" bson:"times_incorrect"`
-	MasteryLevel   float64            `json:"mastery_level" bson:"mastery_level"` // 0-100%
-	LastStudied    time.Time          `json:"last_studied" bson:"last_studied"`
-	LastCorrect    bool               `json:"last_correct" bson:"last_correct"`
+	CardID          string                      `json:"card_id" bson:"card_id"`
+	UserID          primitive.ObjectID          `json:"user_id" bson:"user_id"`
+	CardSetID       primitive.ObjectID          `json:"cardset_id" bson:"cardset_id"`
+	TimesStudied    int                         `json:"times_studied" bson:"times_studied"`
+	TimesCorrect    int                         `json:"times_correct" bson:"times_correct"`
+	TimesIncorrect  int                         `json:"times_incorrect" bson:"times_incorrect"`
+	MasteryLevel    float64                     `json:"mastery_level" bson:"mastery_level"` // 0-100%
+	LastStudied     time.Time                   `json:"last_studied" bson:"last_studied"`
+	LastCorrect     bool                        `json:"last_correct" bson:"last_correct"`
+	ModeStats       map[StudyMode]CardModeStats `json:"mode_stats,omitempty" bson:"mode_stats,omitempty"`         // per study mode breakdown
+	RecentResults   []bool                      `json:"recent_results,omitempty" bson:"recent_results,omitempty"` // latest answers, oldest first
+	AppliedSessions []primitive.ObjectID        `json:"-" bson:"applied_sessions,omitempty"`                      // recent sessions counted, so retries don't count them twice
+	// Spaced-repetition (SM-2) scheduling
+	EaseFactor   float64   `json:"ease_factor" bson:"ease_factor"`
+	IntervalDays int       `json:"interval_days" bson:"interval_days"`
+	Repetitions  int       `json:"repetitions" bson:"repetitions"`
+	Lapses       int       `json:"lapses" bson:"lapses"`
+	DueAt        time.Time `json:"due_at" bson:"due_at"`
+}
+
+// CardModeStats counts the attempts at a card in one study mode
+type CardModeStats struct {
+	TimesStudied int       `json:"times_studied" bson:"times_studied"`
+	TimesCorrect int       `json:"times_correct" bson:"times_correct"`
+	LastStudied  time.Time `json:"last_studied" bson:"last_studied"`
+}
+
+// MasteryBucket classifies a card by how well it was known lately
+type MasteryBucket string
+
+const (
+	MasteryBucketNew       MasteryBucket = "new"       // never studied
+	MasteryBucketLearning  MasteryBucket = "learning"  // mostly missed lately, or not recalled yet
+	MasteryBucketReviewing MasteryBucket = "reviewing" // mostly recalled lately, still being spaced out
+	MasteryBucketMastered  MasteryBucket = "mastered"  // recalled reliably over several spaced reviews
+)
+
+// MasteryBucketCounts counts the cards of a card set per mastery bucket
+type MasteryBucketCounts struct {
+	New       int `json:"new"`
+	Learning  int `json:"learning"`
+	Reviewing int `json:"reviewing"`
+	Mastered  int `json:"mastered"`
+}
+
+// CardMasteryDetail is a card of a set with its mastery bucket.
+// Mastery is missing for cards that were never studied.
+type CardMasteryDetail struct {
+	Card           CardSetCard   `json:"card"`
+	Bucket         MasteryBucket `json:"bucket"`
+	RecentAccuracy float64       `json:"recent_accuracy"` // percentage over the latest answers
+	Mastery        *CardMastery  `json:"mastery,omitempty"`
+}
+
+// CardSetMasteryResponse breaks the cards of a set down by mastery bucket
+type CardSetMasteryResponse struct {
+	CardSetID primitive.ObjectID  `json:"cardset_id"`
+	Buckets   MasteryBucketCounts `json:"buckets"`
+	Cards     []CardMasteryDetail `json:"cards"`
+}
+
+// DueCard represents a card that is due for review
+type DueCard struct {
+	CardSetID    primitive.ObjectID `json:"cardset_id"`
+	CardSetTitle string             `json:"cardset_title"`
+	Card         CardSetCard        `json:"card"`
+	Mastery      CardMastery        `json:"mastery"`
 }
 
 // UserStatistics represents aggregate statistics for a user
 type UserStatistics struct {
 	ID                primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	UserID            primitive.ObjectID `json:"user_id" bson:"user_id"`
-	TotalStudyTime    int                `json:"total_study_time" bson:"total_study_time"`       // in seconds
+	TotalStudyTime    int                `json:"total_study_time" bson:"total_study_time"` // in seconds
 	TotalSessions     int                `json:"total_sessions" bson:"total_sessions"`
 	TotalCardsStudied int                `json:"total_cards_studied" bson:"total_cards_studied"` // unique cards
 	TotalAttempts     int                `json:"total_attempts" bson:"total_attempts"`           // all attempts
 	OverallAccuracy   float64            `json:"overall_accuracy" bson:"overall_accuracy"`
-	CurrentStreak     int                `json:"current_streak" bson:"current_streak"`     // consecutive days
+	CurrentStreak     int                `json:"current_streak" bson:"current_streak"` // consecutive days
 	LongestStreak     int                `json:"longest_streak" bson:"longest_streak"`
+	StreakFreezes     []time.Time        `json:"streak_freezes,omitempty" bson:"streak_freezes,omitempty"` // days that don't break the streak
 	LastStudyDate     time.Time          `json:"last_study_date" bson:"last_study_date"`
 	DailyStats        []DailyStats       `json:"daily_stats" bson:"daily_stats"`
-	CardsMastered     int                `json:"cards_mastered" bson:"cards_mastered"`     // cards with >80% accuracy
-	CardsLearning     int                `json:"cards_learning" bson:"cards_learning"`     // cards with 50-80% accuracy
-	CardsNew          int                `json:"cards_new" bson:"cards_new"`               // cards with <50% accuracy or never studied
+	CardsMastered     int                `json:"cards_mastered" bson:"cards_mastered"` // cards with >80% accuracy
+	CardsLearning     int                `json:"cards_learning" bson:"cards_learning"` // cards with 50-80% accuracy
+	CardsNew          int                `json:"cards_new" bson:"cards_new"`           // cards with <50% accuracy or never studied
+	RecalculatedAt    time.Time          `json:"-" bson:"recalculated_at,omitempty"`   // start of the recalculation that wrote these statistics
+	RecalculateJob    *StatsJob          `json:"-" bson:"recalculate_job,omitempty"`   // set while a recalculation is queued
 	UpdatedAt         time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// FreezeStreakRequest freezes a day (YYYY-MM-DD in the user's timezone), today or later
+type FreezeStreakRequest struct {
+	Date string `json:"date" binding:"required"`
+}
+
+// RebuildStatisticsRequest rebuilds the statistics of one user, or of all users
+type RebuildStatisticsRequest struct {
+	UserID string `json:"user_id"`
+	All    bool   `json:"all"`
+	DryRun bool   `json:"dry_run"`
+}
+
+// FailedStatisticsJob is a session the statistics worker gave up on
+type FailedStatisticsJob struct {
+	SessionID primitive.ObjectID `json:"session_id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	CardSetID primitive.ObjectID `json:"cardset_id" bson:"cardset_id"`
+	Attempts  int                `json:"attempts" bson:"attempts"`
+	LastError string             `json:"last_error" bson:"last_error"`
+	FailedAt  *time.Time         `json:"failed_at,omitempty" bson:"failed_at,omitempty"` // unset when the last attempt's worker died
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// RetryStatisticsJobsRequest requeues the failed statistics jobs of one user, or of all users when user_id is empty
+type RetryStatisticsJobsRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// StatisticsRebuildReport compares a user's stored statistics with the ones replayed from their sessions
+type StatisticsRebuildReport struct {
+	UserID       primitive.ObjectID `json:"user_id"`
+	Sessions     int                `json:"sessions"`
+	DryRun       bool               `json:"dry_run"`
+	Changes      []StatisticsChange `json:"changes"` // user statistics fields that differ
+	CardsAdded   int                `json:"cards_added"`
+	CardsChanged int                `json:"cards_changed"`
+	CardsRemoved int                `json:"cards_removed"`
+}
+
+// StatisticsChange is a user statistics field whose rebuilt value differs from the stored one
+type StatisticsChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// StatisticsRebuildProgress is streamed once per user while rebuilding statistics
+type StatisticsRebuildProgress struct {
+	Done   int                      `json:"done"`
+	Total  int                      `json:"total"`
+	UserID primitive.ObjectID       `json:"user_id"`
+	Report *StatisticsRebuildReport `json:"report,omitempty"`
+	Error  string                   `json:"error,omitempty"`
+}
+
+// TimeseriesGranularity is the length of the buckets of a statistics timeseries
+type TimeseriesGranularity string
+
+const (
+	TimeseriesDay   TimeseriesGranularity = "day"
+	TimeseriesWeek  TimeseriesGranularity = "week" // weeks start on Monday
+	TimeseriesMonth TimeseriesGranularity = "month"
+)
+
+// TimeseriesBucket sums up the sessions started in one bucket.
+// Date is the first day of the bucket in the user's timezone, as midnight UTC like daily stats.
+type TimeseriesBucket struct {
+	Date         time.Time `json:"date"`
+	Sessions     int       `json:"sessions"`
+	StudyTime    int       `json:"study_time"` // in seconds
+	CardsStudied int       `json:"cards_studied"`
+	Accuracy     float64   `json:"accuracy"`
+}
+
+// TimeseriesResponse lists every bucket between from and to, including empty ones.
+// From and To are widened to the first and last day of the outer buckets.
+type TimeseriesResponse struct {
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	Granularity TimeseriesGranularity `json:"granularity"`
+	Timezone    string                `json:"timezone"`
+	Buckets     []TimeseriesBucket    `json:"buckets"`
+}
+
 // PerformanceByMode represents statistics grouped by study mode
 type PerformanceByMode struct {
 	Mode         StudyMode `json:"mode" bson:"mode"`
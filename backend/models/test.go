@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestStatus constants
+const (
+	TestStatusPending   = "pending"
+	TestStatusSubmitted = "submitted"
+)
+
+// MatchItem is one prompt of a matching question
+type MatchItem struct {
+	CardID string `json:"card_id" bson:"card_id"`
+	Prompt string `json:"prompt" bson:"prompt"`
+	Answer string `json:"-" bson:"answer"`
+}
+
+// TestQuestion is a generated test question. Expected answers never leave the server before submission.
+type TestQuestion struct {
+	ID         string       `json:"id" bson:"id"`
+	CardID     string       `json:"card_id,omitempty" bson:"card_id,omitempty"`
+	Type       QuestionType `json:"type" bson:"type"`
+	AnswerWith string       `json:"answer_with" bson:"answer_with"`
+	Prompt     string       `json:"prompt,omitempty" bson:"prompt,omitempty"`
+	Statement  string       `json:"statement,omitempty" bson:"statement,omitempty"` // candidate answer for true/false
+	Options    []string     `json:"options,omitempty" bson:"options,omitempty"`
+	Items      []MatchItem  `json:"items,omitempty" bson:"items,omitempty"` // matching only
+	Answer     string       `json:"-" bson:"answer,omitempty"`
+}
+
+// CardTest represents a server-generated test for a card set
+type CardTest struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	CardSetID      primitive.ObjectID  `json:"cardset_id" bson:"cardset_id"`
+	Status         string              `json:"status" bson:"status"`
+	Questions      []TestQuestion      `json:"questions" bson:"questions"`
+	Score          float64             `json:"score" bson:"score"` // percentage
+	StudySessionID *primitive.ObjectID `json:"study_session_id,omitempty" bson:"study_session_id,omitempty"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	SubmittedAt    *time.Time          `json:"submitted_at,omitempty" bson:"submitted_at,omitempty"`
+}
+
+type CreateTestRequest struct {
+	QuestionCount int            `json:"question_count" binding:"omitempty,min=1,max=200"`
+	QuestionTypes []QuestionType `json:"question_types" binding:"omitempty,dive,oneof=multiple_choice true_false written matching"`
+	AnswerWith    string         `json:"answer_with" binding:"omitempty,oneof=definition term both"`
+}
+
+// TestAnswer is the learner's answer to one question. Matching questions use Matches (card_id -> option).
+type TestAnswer struct {
+	QuestionID string            `json:"question_id" binding:"required"`
+	Answer     string            `json:"answer" binding:"max=2000"`
+	Matches    map[string]string `json:"matches"`
+}
+
+type SubmitTestRequest struct {
+	Answers []TestAnswer `json:"answers" binding:"dive"`
+}
+
+// TestQuestionResult is the graded result of one question (one entry per card for matching)
+type TestQuestionResult struct {
+	QuestionID    string `json:"question_id"`
+	CardID        string `json:"card_id"`
+	Correct       bool   `json:"correct"`
+	UserAnswer    string `json:"user_answer"`
+	CorrectAnswer string `json:"correct_answer"`
+}
+
+type SubmitTestResponse struct {
+	Score        float64              `json:"score"`
+	Correct      int                  `json:"correct"`
+	Total        int                  `json:"total"`
+	Results      []TestQuestionResult `json:"results"`
+	StudySession StudySession         `json:"study_session"`
+}
@@ -6,6 +6,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User roles. Users without a role are students; teachers are granted by an admin and admins are only
+// assigned in the database.
+const (
+	UserRoleStudent = "student"
+	UserRoleTeacher = "teacher"
+	UserRoleAdmin   = "admin"
+)
+
 type User struct {
 	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Username         string             `json:"username" bson:"username" binding:"required,min=3,max=50"`
@@ -15,10 +23,20 @@ type User struct {
 	Avatar           string             `json:"avatar" bson:"avatar" binding:"max=500"`
 	DateOfBirth      *time.Time         `json:"date_of_birth,omitempty" bson:"date_of_birth,omitempty"`
 	PreferredVoiceID string             `json:"preferred_voice_id,omitempty" bson:"preferred_voice_id,omitempty" binding:"max=50"`
+	Role             string             `json:"role,omitempty" bson:"role,omitempty"`
+	Timezone         string             `json:"timezone,omitempty" bson:"timezone,omitempty"` // IANA name, days are bucketed in it
 	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt        time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// PublicProfile is the part of a user shown to other users
+type PublicProfile struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	Username string             `json:"username" bson:"username"`
+	FullName string             `json:"full_name" bson:"full_name"`
+	Avatar   string             `json:"avatar" bson:"avatar"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,max=255"`
 	Password string `json:"password" binding:"required,min=1,max=100"`
@@ -43,4 +61,11 @@ type UpdateProfileRequest struct {
 	Avatar           string     `json:"avatar" binding:"max=500"`
 	DateOfBirth      *time.Time `json:"date_of_birth"`
 	PreferredVoiceID string     `json:"preferred_voice_id" binding:"max=50"`
+	Timezone         string     `json:"timezone" binding:"max=64"`
+}
+
+// UpdateUserRoleRequest is sent by an admin to grant or revoke the teacher role.
+// A changed role takes effect in access tokens issued after the next refresh.
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=student teacher"`
 }
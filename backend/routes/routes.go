@@ -5,6 +5,7 @@ import (
 	"learn-backend/config"
 	"learn-backend/controllers"
 	"learn-backend/middleware"
+	"learn-backend/models"
 	"log"
 	"net/http"
 	"time"
@@ -29,6 +30,18 @@ func SetupRoutes(router *gin.Engine, db *mongo.Database, cfg *config.Config) {
 	updateCampaignController := controllers.NewUpdateCampaignController()
 	v1.GET("/update-campaign", updateCampaignController.GetUpdateCampaign)
 
+	// Card images (public, loaded by <img> tags)
+	mediaController := controllers.NewMediaController(db, cfg)
+	v1.GET("/media/:id", mediaController.GetMedia)
+
+	// Share link previews (public)
+	shareController := controllers.NewShareController(db)
+	v1.GET("/shares/:token", shareController.GetSharePreview)
+
+	// Public card set previews for landing pages
+	publicCardSetController := controllers.NewCardSetController(db, cfg)
+	v1.GET("/public/cardsets/:id", publicCardSetController.GetPublicCardSet)
+
 	// Auth routes (public)
 	authController := controllers.NewAuthController(db, cfg)
 	loginOrRegisterController := controllers.NewLoginOrRegisterController(db, cfg)
@@ -51,18 +64,124 @@ func SetupRoutes(router *gin.Engine, db *mongo.Database, cfg *config.Config) {
 		protected.POST("/auth/logout-all", authController.LogoutAll)
 
 		// Card sets
-		cardSetController := controllers.NewCardSetController(db)
+		cardSetController := controllers.NewCardSetController(db, cfg)
+		testController := controllers.NewTestController(db)
+		folderController := controllers.NewFolderController(db)
+		collaboratorController := controllers.NewCollaboratorController(db)
 		cardSets := protected.Group("/cardsets")
 		{
 			cardSets.GET("", cardSetController.GetCardSets)
 			cardSets.GET("/global", cardSetController.GetGlobalCardSets)
+			cardSets.GET("/global/:id", cardSetController.GetPublicCardSet)
+			cardSets.GET("/trash", cardSetController.GetTrash)
+			cardSets.GET("/tags", cardSetController.GetTags)
+			cardSets.GET("/shared", collaboratorController.GetSharedCardSets)
+			cardSets.GET("/invitations", collaboratorController.GetInvitations)
 			cardSets.GET("/:id", cardSetController.GetCardSet)
 			cardSets.POST("", cardSetController.CreateCardSet)
 			cardSets.PUT("/:id", cardSetController.UpdateCardSet)
 			cardSets.DELETE("/:id", cardSetController.DeleteCardSet)
+			cardSets.POST("/:id/restore", cardSetController.RestoreCardSet)
 			cardSets.POST("/:id/publish", cardSetController.TogglePublish)
 			cardSets.POST("/:id/import", cardSetController.ImportFromGlobal)
 			cardSets.POST("/:id/generate-phonetics", cardSetController.GeneratePhonetics)
+
+			// Individual cards
+			cardSets.POST("/:id/cards", cardSetController.AddCards)
+			cardSets.POST("/:id/cards/reorder", cardSetController.ReorderCards)
+			cardSets.PATCH("/:id/cards/:cardId", cardSetController.UpdateCard)
+			cardSets.DELETE("/:id/cards/:cardId", cardSetController.DeleteCard)
+
+			// CSV/TSV and Anki import and export
+			importController := controllers.NewCardSetImportController(db, cfg)
+			cardSets.POST("/import", importController.ImportCardSet)
+			cardSets.GET("/:id/export", importController.ExportCardSet)
+
+			// Share links
+			cardSets.POST("/:id/shares", shareController.CreateShare)
+			cardSets.GET("/:id/shares", shareController.GetShares)
+			cardSets.DELETE("/:id/shares/:shareId", shareController.DeleteShare)
+
+			// Ratings, reviews and reports
+			ratingController := controllers.NewRatingController(db, cfg)
+			cardSets.POST("/:id/ratings", ratingController.RateCardSet)
+			cardSets.GET("/:id/reviews", ratingController.GetReviews)
+			cardSets.POST("/:id/report", ratingController.ReportCardSet)
+
+			// Moving sets between folders
+			cardSets.POST("/move", folderController.MoveCardSets)
+
+			// Collaborators
+			cardSets.GET("/:id/collaborators", collaboratorController.GetCollaborators)
+			cardSets.POST("/:id/collaborators", collaboratorController.InviteCollaborator)
+			cardSets.POST("/:id/collaborators/accept", collaboratorController.AcceptInvitation)
+			cardSets.PUT("/:id/collaborators/:userId", collaboratorController.UpdateCollaborator)
+			cardSets.DELETE("/:id/collaborators/:userId", collaboratorController.RemoveCollaborator)
+
+			// Upstream sync of forked card sets
+			forkController := controllers.NewForkController(db, cfg)
+			cardSets.GET("/:id/upstream-diff", forkController.GetUpstreamDiff)
+			cardSets.POST("/:id/sync-upstream", forkController.SyncUpstream)
+
+			// Revision history
+			revisionController := controllers.NewRevisionController(db, cfg)
+			cardSets.GET("/:id/revisions", revisionController.GetRevisions)
+			cardSets.GET("/:id/revisions/:rev", revisionController.GetRevision)
+			cardSets.POST("/:id/revisions/:rev/restore", revisionController.RestoreRevision)
+
+			// Learn mode sessions
+			learnController := controllers.NewLearnController(db)
+			cardSets.POST("/:id/learn-sessions", learnController.CreateLearnSession)
+			cardSets.GET("/:id/learn-sessions/:sessionId", learnController.GetLearnSession)
+			cardSets.GET("/:id/learn-sessions/:sessionId/next", learnController.GetNextQuestion)
+			cardSets.POST("/:id/learn-sessions/:sessionId/answer", learnController.SubmitAnswer)
+
+			// Tests
+			cardSets.POST("/:id/tests", testController.CreateTest)
+
+			// Answer checking
+			gradingController := controllers.NewGradingController(db)
+			cardSets.POST("/:id/cards/:cardId/check", gradingController.CheckAnswer)
+		}
+
+		protected.POST("/shares/:token/import", shareController.ImportShare)
+
+		// Folders
+		folders := protected.Group("/folders")
+		{
+			folders.GET("", folderController.GetFolders)
+			folders.POST("", folderController.CreateFolder)
+			folders.PUT("/:id", folderController.UpdateFolder)
+			folders.DELETE("/:id", folderController.DeleteFolder)
+		}
+
+		tests := protected.Group("/tests")
+		{
+			tests.GET("/:id", testController.GetTest)
+			tests.POST("/:id/submit", testController.SubmitTest)
+		}
+
+		// Classes; managing a class needs the teacher role
+		classController := controllers.NewClassController(db)
+		classes := protected.Group("/classes")
+		{
+			classes.GET("", classController.GetClasses)
+			classes.POST("/join", classController.JoinClass)
+			classes.GET("/:id", classController.GetClass)
+			classes.POST("/:id/leave", classController.LeaveClass)
+			classes.GET("/:id/assignments", classController.GetAssignments)
+			classes.POST("/:id/assignments/:assignmentId/start", classController.StartAssignment)
+
+			teaching := classes.Group("", middleware.RequireRole(models.UserRoleTeacher))
+			teaching.POST("", classController.CreateClass)
+			teaching.PUT("/:id", classController.UpdateClass)
+			teaching.DELETE("/:id", classController.DeleteClass)
+			teaching.POST("/:id/join-code", classController.ResetJoinCode)
+			teaching.DELETE("/:id/students/:userId", classController.RemoveStudent)
+			teaching.POST("/:id/assignments", classController.CreateAssignment)
+			teaching.PUT("/:id/assignments/:assignmentId", classController.UpdateAssignment)
+			teaching.DELETE("/:id/assignments/:assignmentId", classController.DeleteAssignment)
+			teaching.GET("/:id/report", classController.GetClassReport)
 		}
 
 		// Statistics
@@ -71,7 +190,26 @@ func SetupRoutes(router *gin.Engine, db *mongo.Database, cfg *config.Config) {
 		{
 			statistics.POST("/sessions", statisticsController.RecordStudySession)
 			statistics.GET("", statisticsController.GetUserStatistics)
+			statistics.GET("/timeseries", statisticsController.GetTimeseries)
 			statistics.GET("/cardsets/:id", statisticsController.GetCardSetStatistics)
+			statistics.GET("/cardsets/:id/mastery", statisticsController.GetCardSetMastery)
+			statistics.POST("/streak-freezes", statisticsController.FreezeStreak)
+		}
+
+		// Administration
+		admin := protected.Group("/admin", middleware.RequireRole(models.UserRoleAdmin))
+		{
+			admin.POST("/statistics/rebuild", statisticsController.RebuildStatistics)
+			admin.GET("/statistics/failed-jobs", statisticsController.GetFailedStatisticsJobs)
+			admin.POST("/statistics/failed-jobs/retry", statisticsController.RetryFailedStatisticsJobs)
+			admin.PUT("/users/:id/role", authController.UpdateUserRole)
+		}
+
+		// Spaced-repetition reviews
+		reviewController := controllers.NewReviewController(db)
+		reviews := protected.Group("/reviews")
+		{
+			reviews.GET("/due", reviewController.GetDueReviews)
 		}
 
 		// Image search
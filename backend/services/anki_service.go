@@ -0,0 +1,689 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"learn-backend/models"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+// ExportFormatAPKG is the Anki package export format
+const ExportFormatAPKG = "apkg"
+
+// Export limits: images are fetched a few at a time within a shared budget,
+// and the collection is written under its own timeout
+const (
+	ankiMediaFetchConcurrency  = 8
+	ankiMediaFetchBudget       = 20 * time.Second
+	ankiCollectionWriteTimeout = 20 * time.Second
+)
+
+// maxAnkiCollectionSize caps the uncompressed collection of an imported package
+const maxAnkiCollectionSize = 200 << 20
+
+var (
+	// ErrInvalidAnkiPackage is returned for uploads that are not Anki packages
+	ErrInvalidAnkiPackage = errors.New("file is not a valid Anki package")
+	// ErrAnkiPackageTooLarge is returned for packages whose collection unpacks beyond maxAnkiCollectionSize
+	ErrAnkiPackageTooLarge = errors.New("the Anki collection in this package is too large")
+	// ErrUnsupportedAnkiPackage is returned for packages using the newer compressed collection format
+	ErrUnsupportedAnkiPackage = errors.New("this Anki package format is not supported, export it with \"Support older Anki versions\" enabled")
+)
+
+// AnkiNote is one note of an imported package with its fields in model order
+type AnkiNote struct {
+	Number     int
+	FieldNames []string
+	Fields     []string
+}
+
+// AnkiPackage is a parsed .apkg file. Media files are read lazily from the zip.
+type AnkiPackage struct {
+	Notes []AnkiNote
+	media map[string]*zip.File
+}
+
+// AnkiImportedCard is a card mapped from a note plus the package media file of its image, if any
+type AnkiImportedCard struct {
+	Row       int
+	Card      models.CardSetCard
+	ImageFile string
+}
+
+// Media reads a media file of the package by its name in the collection
+func (p *AnkiPackage) Media(name string) (*MediaFile, error) {
+	file, ok := p.media[name]
+	if !ok {
+		return nil, fmt.Errorf("media file %q not found in package", name)
+	}
+	if file.UncompressedSize64 > MaxMediaSize {
+		return nil, ErrUnsupportedMedia
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, MaxMediaSize+1))
+	if err != nil {
+		return nil, err
+	}
+	return &MediaFile{Filename: name, Data: data}, nil
+}
+
+// ankiFieldAliases maps common Anki field names to card fields for the default mapping
+var ankiFieldAliases = map[string]string{
+	"front":          "terminology",
+	"term":           "terminology",
+	"word":           "terminology",
+	"back":           "define",
+	"definition":     "define",
+	"meaning":        "define",
+	"example":        "example",
+	"sentence":       "example",
+	"partofspeech":   "part_of_speech",
+	"part of speech": "part_of_speech",
+	"pos":            "part_of_speech",
+	"phonetic":       "phonetic",
+	"ipa":            "phonetic",
+	"pronunciation":  "phonetic",
+	"image":          "image_url",
+	"picture":        "image_url",
+}
+
+var (
+	ankiImageRegexp = regexp.MustCompile(`(?i)<img[^>]*\ssrc=["']?([^"'\s>]+)`)
+	ankiSoundRegexp = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	ankiBreakRegexp = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>|</li>`)
+	ankiTagRegexp   = regexp.MustCompile(`<[^>]*>`)
+	ankiBlankLines  = regexp.MustCompile(`\n{2,}`)
+)
+
+type AnkiService struct{}
+
+func NewAnkiService() *AnkiService {
+	return &AnkiService{}
+}
+
+// ReadPackage opens an .apkg file and loads its notes
+func (s *AnkiService) ReadPackage(r io.ReaderAt, size int64) (*AnkiPackage, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	// collection.anki21 is preferred when present; a package holding only
+	// collection.anki21b ships a placeholder collection.anki2
+	collection := files["collection.anki21"]
+	if collection == nil {
+		if files["collection.anki21b"] != nil {
+			return nil, ErrUnsupportedAnkiPackage
+		}
+		collection = files["collection.anki2"]
+	}
+	if collection == nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+
+	pkg := &AnkiPackage{media: map[string]*zip.File{}}
+	if mediaFile := files["media"]; mediaFile != nil {
+		mediaMap, err := readAnkiMediaMap(mediaFile)
+		if err != nil {
+			return nil, err
+		}
+		for index, name := range mediaMap {
+			if file := files[index]; file != nil {
+				pkg.media[name] = file
+			}
+		}
+	}
+
+	pkg.Notes, err = readAnkiNotes(collection)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+func readAnkiMediaMap(file *zip.File) (map[string]string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+	defer reader.Close()
+
+	mediaMap := map[string]string{}
+	if err := json.NewDecoder(reader).Decode(&mediaMap); err != nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+	return mediaMap, nil
+}
+
+// readAnkiNotes copies the SQLite collection to a temporary file and reads the notes and field names
+func readAnkiNotes(collection *zip.File) ([]AnkiNote, error) {
+	tmp, err := os.CreateTemp("", "anki-import-*.sqlite")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if collection.UncompressedSize64 > maxAnkiCollectionSize {
+		tmp.Close()
+		return nil, ErrAnkiPackageTooLarge
+	}
+
+	reader, err := collection.Open()
+	if err != nil {
+		tmp.Close()
+		return nil, ErrInvalidAnkiPackage
+	}
+	// The declared size can lie, so the copy is capped as well
+	written, err := io.Copy(tmp, io.LimitReader(reader, maxAnkiCollectionSize+1))
+	reader.Close()
+	tmp.Close()
+	if err != nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+	if written > maxAnkiCollectionSize {
+		return nil, ErrAnkiPackageTooLarge
+	}
+
+	db, err := sql.Open("sqlite", tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var modelsJSON string
+	if err := db.QueryRow("SELECT models FROM col LIMIT 1").Scan(&modelsJSON); err != nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+
+	var noteTypes map[string]struct {
+		Flds []struct {
+			Name string `json:"name"`
+			Ord  int    `json:"ord"`
+		} `json:"flds"`
+	}
+	if err := json.Unmarshal([]byte(modelsJSON), &noteTypes); err != nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+
+	fieldNames := map[int64][]string{}
+	for id, noteType := range noteTypes {
+		modelID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		sort.Slice(noteType.Flds, func(i, j int) bool { return noteType.Flds[i].Ord < noteType.Flds[j].Ord })
+		names := make([]string, len(noteType.Flds))
+		for i, field := range noteType.Flds {
+			names[i] = field.Name
+		}
+		fieldNames[modelID] = names
+	}
+
+	rows, err := db.Query("SELECT mid, flds FROM notes ORDER BY id")
+	if err != nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+	defer rows.Close()
+
+	notes := []AnkiNote{}
+	for rows.Next() {
+		var modelID int64
+		var fields string
+		if err := rows.Scan(&modelID, &fields); err != nil {
+			return nil, ErrInvalidAnkiPackage
+		}
+		notes = append(notes, AnkiNote{
+			Number:     len(notes) + 1,
+			FieldNames: fieldNames[modelID],
+			Fields:     strings.Split(fields, "\x1f"),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, ErrInvalidAnkiPackage
+	}
+
+	return notes, nil
+}
+
+// ParseFieldMapping validates a JSON object of note field name to card field
+func ParseFieldMapping(value string) (map[string]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	mapping := map[string]string{}
+	if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+		return nil, fmt.Errorf("field_mapping must be a JSON object of note field to card field")
+	}
+	for field, column := range mapping {
+		if _, ok := cardColumnLimits[column]; !ok && column != "" {
+			return nil, fmt.Errorf("unknown card field %q for note field %q", column, field)
+		}
+	}
+	return mapping, nil
+}
+
+// ToCards maps notes onto cards. Without a mapping, fields are matched by
+// their name and otherwise the first two fields become term and definition.
+func (s *AnkiService) ToCards(pkg *AnkiPackage, mapping map[string]string) ([]AnkiImportedCard, []models.ImportRowError) {
+	cards := []AnkiImportedCard{}
+	rowErrors := []models.ImportRowError{}
+
+	for _, note := range pkg.Notes {
+		card, err := ankiNoteToCard(note, mapping)
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: note.Number, Error: err.Error()})
+			continue
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rowErrors
+}
+
+func ankiNoteToCard(note AnkiNote, mapping map[string]string) (AnkiImportedCard, error) {
+	imported := AnkiImportedCard{Row: note.Number, Card: models.CardSetCard{ID: uuid.New().String()}}
+	card := &imported.Card
+	firstImage := ""
+
+	for i, raw := range note.Fields {
+		name := ""
+		if i < len(note.FieldNames) {
+			name = note.FieldNames[i]
+		}
+
+		if match := ankiImageRegexp.FindStringSubmatch(raw); match != nil && firstImage == "" {
+			firstImage = html.UnescapeString(match[1])
+		}
+
+		column, ok := mapping[name]
+		if mapping == nil {
+			column, ok = ankiFieldAliases[strings.ToLower(strings.TrimSpace(name))]
+			if !ok && i < 2 {
+				column, ok = []string{"terminology", "define"}[i], true
+			}
+		}
+		if !ok || column == "" {
+			continue
+		}
+
+		if column == "image_url" {
+			if match := ankiImageRegexp.FindStringSubmatch(raw); match != nil {
+				imported.ImageFile = html.UnescapeString(match[1])
+			}
+			continue
+		}
+
+		value := ankiFieldText(raw)
+		if limit := cardColumnLimits[column]; utf8.RuneCountInString(value) > limit {
+			return imported, fmt.Errorf("%s is longer than %d characters", column, limit)
+		}
+
+		switch column {
+		case "terminology":
+			card.Terminology = value
+		case "define":
+			card.Define = value
+		case "example":
+			card.Example = value
+		case "part_of_speech":
+			card.PartOfSpeech = value
+		case "phonetic":
+			card.Phonetic = value
+		}
+	}
+
+	if imported.ImageFile == "" {
+		imported.ImageFile = firstImage
+	}
+	// Remote images can be linked directly
+	if strings.HasPrefix(imported.ImageFile, "http://") || strings.HasPrefix(imported.ImageFile, "https://") {
+		if len(imported.ImageFile) <= cardColumnLimits["image_url"] {
+			card.ImageURL = imported.ImageFile
+		}
+		imported.ImageFile = ""
+	}
+
+	if card.Terminology == "" {
+		return imported, fmt.Errorf("terminology is required")
+	}
+	if card.Define == "" {
+		return imported, fmt.Errorf("define is required")
+	}
+
+	return imported, nil
+}
+
+// ankiFieldText turns the HTML of a note field into plain text
+func ankiFieldText(raw string) string {
+	text := ankiSoundRegexp.ReplaceAllString(raw, "")
+	text = ankiBreakRegexp.ReplaceAllString(text, "\n")
+	text = ankiTagRegexp.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	text = ankiBlankLines.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(text)
+}
+
+// ankiExportFields are the fields of the note type written by WritePackage
+var ankiExportFields = []string{"Term", "Definition", "Example", "PartOfSpeech", "Phonetic", "Image"}
+
+const ankiSchema = `
+CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null);
+CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null);
+CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null);
+CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn on notes (usn);
+CREATE INDEX ix_cards_usn on cards (usn);
+CREATE INDEX ix_revlog_usn on revlog (usn);
+CREATE INDEX ix_cards_nid on cards (nid);
+CREATE INDEX ix_cards_sched on cards (did, queue, due);
+CREATE INDEX ix_revlog_cid on revlog (cid);
+CREATE INDEX ix_notes_csum on notes (csum);
+`
+
+const ankiCardCSS = `.card { font-family: arial; font-size: 20px; text-align: center; color: black; background-color: white; }
+.phonetic, .pos { color: #888; font-size: 16px; }
+.example { font-style: italic; font-size: 16px; margin-top: 12px; }`
+
+// WritePackage writes the card set as an .apkg with one note per card. Images are
+// loaded through fetchImage; cards whose image can't be loaded in time are exported without it.
+func (s *AnkiService) WritePackage(ctx context.Context, w io.Writer, cardSet *models.CardSet, fetchImage func(ctx context.Context, url string) (*MediaFile, error)) error {
+	tmp, err := os.CreateTemp("", "anki-export-*.sqlite")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpName)
+
+	files := fetchAnkiMedia(ctx, cardSet.Cards, fetchImage)
+
+	media := []*MediaFile{}
+	imageFields := make([]string, len(cardSet.Cards))
+	for i, file := range files {
+		if file == nil {
+			continue
+		}
+		file.Filename = ankiMediaName(len(media), file)
+		media = append(media, file)
+		imageFields[i] = fmt.Sprintf(`<img src="%s">`, html.EscapeString(file.Filename))
+	}
+
+	// Slow images must not eat into the time needed to write the collection
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), ankiCollectionWriteTimeout)
+	defer cancel()
+	if err := writeAnkiCollection(writeCtx, tmpName, cardSet, imageFields); err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+
+	collection, err := os.Open(tmpName)
+	if err != nil {
+		return err
+	}
+	defer collection.Close()
+	entry, err := archive.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(entry, collection); err != nil {
+		return err
+	}
+
+	mediaMap := map[string]string{}
+	for i, file := range media {
+		index := strconv.Itoa(i)
+		mediaMap[index] = file.Filename
+		entry, err := archive.Create(index)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(file.Data); err != nil {
+			return err
+		}
+	}
+
+	entry, err = archive.Create("media")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(entry).Encode(mediaMap); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// fetchAnkiMedia loads the card images concurrently, indexed like the cards.
+// Images that fail or don't arrive within the fetch budget are left nil.
+func fetchAnkiMedia(ctx context.Context, cards []models.CardSetCard, fetchImage func(ctx context.Context, url string) (*MediaFile, error)) []*MediaFile {
+	ctx, cancel := context.WithTimeout(ctx, ankiMediaFetchBudget)
+	defer cancel()
+
+	files := make([]*MediaFile, len(cards))
+	slots := make(chan struct{}, ankiMediaFetchConcurrency)
+	var wg sync.WaitGroup
+	for i, card := range cards {
+		if card.ImageURL == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			if file, err := fetchImage(ctx, card.ImageURL); err == nil {
+				files[i] = file
+			}
+		}()
+	}
+	wg.Wait()
+
+	return files
+}
+
+// ankiMediaName gives every exported image a unique file name with a matching extension
+func ankiMediaName(index int, file *MediaFile) string {
+	ext := path.Ext(file.Filename)
+	if ext == "" || len(ext) > 5 {
+		ext = "." + strings.TrimPrefix(strings.SplitN(file.ContentType, ";", 2)[0], "image/")
+	}
+	return fmt.Sprintf("learn-%d%s", index, strings.ToLower(ext))
+}
+
+func writeAnkiCollection(ctx context.Context, filename string, cardSet *models.CardSet, imageFields []string) error {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, ankiSchema); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	nowSec, nowMs := now.Unix(), now.UnixMilli()
+	modelID := nowMs
+	deckID := nowMs + 1
+
+	confJSON, modelsJSON, decksJSON, dconfJSON, err := ankiCollectionJSON(cardSet, modelID, deckID, nowSec)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')",
+		nowSec, nowMs, nowMs, confJSON, modelsJSON, decksJSON, dconfJSON,
+	)
+	if err != nil {
+		return err
+	}
+
+	for i, card := range cardSet.Cards {
+		fields := []string{
+			ankiFieldHTML(card.Terminology),
+			ankiFieldHTML(card.Define),
+			ankiFieldHTML(card.Example),
+			ankiFieldHTML(card.PartOfSpeech),
+			ankiFieldHTML(card.Phonetic),
+			imageFields[i],
+		}
+
+		noteID := nowMs + int64(i)
+		sum := sha1.Sum([]byte(card.Terminology))
+		checksum := int64(binary.BigEndian.Uint32(sum[:4]))
+
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO notes VALUES (?, ?, ?, ?, -1, '', ?, ?, ?, 0, '')",
+			noteID, ankiGUID(card.ID), modelID, nowSec, strings.Join(fields, "\x1f"), card.Terminology, checksum,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')",
+			noteID, noteID, deckID, nowSec, i+1,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func ankiCollectionJSON(cardSet *models.CardSet, modelID, deckID, nowSec int64) (conf, noteTypes, decks, dconf string, err error) {
+	flds := []map[string]interface{}{}
+	for i, name := range ankiExportFields {
+		flds = append(flds, map[string]interface{}{
+			"name": name, "ord": i, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{},
+		})
+	}
+
+	noteType := map[string]interface{}{
+		"id":    modelID,
+		"name":  "Learn Basic",
+		"type":  0,
+		"mod":   nowSec,
+		"usn":   -1,
+		"sortf": 0,
+		"did":   deckID,
+		"tmpls": []map[string]interface{}{{
+			"name":  "Card 1",
+			"ord":   0,
+			"qfmt":  `{{Term}}{{#Phonetic}}<div class="phonetic">{{Phonetic}}</div>{{/Phonetic}}`,
+			"afmt":  `{{FrontSide}}<hr id="answer">{{Definition}}{{#PartOfSpeech}}<div class="pos">{{PartOfSpeech}}</div>{{/PartOfSpeech}}{{#Example}}<div class="example">{{Example}}</div>{{/Example}}{{#Image}}<div>{{Image}}</div>{{/Image}}`,
+			"did":   nil,
+			"bqfmt": "",
+			"bafmt": "",
+		}},
+		"flds":      flds,
+		"css":       ankiCardCSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"tags":      []string{},
+		"vers":      []int{},
+		"req":       []interface{}{[]interface{}{0, "any", []int{0}}},
+	}
+
+	deck := func(id int64, name, desc string) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "name": name, "desc": desc, "mod": nowSec, "usn": -1,
+			"collapsed": false, "browserCollapsed": false,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+			"dyn": 0, "conf": 1, "extendNew": 0, "extendRev": 0,
+		}
+	}
+
+	deckConf := map[string]interface{}{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+		"new":   map[string]interface{}{"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": false, "separate": true},
+		"rev":   map[string]interface{}{"perDay": 200, "ease4": 1.3, "ivlFct": 1, "maxIvl": 36500, "fuzz": 0.05, "bury": false, "hardFactor": 1.2, "minSpace": 1},
+		"lapse": map[string]interface{}{"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0},
+	}
+
+	collectionConf := map[string]interface{}{
+		"nextPos": len(cardSet.Cards) + 1, "estTimes": true, "activeDecks": []int64{1}, "sortType": "noteFld", "timeLim": 0,
+		"sortBackwards": false, "addToCur": true, "curDeck": 1, "newSpread": 0, "dueCounts": true, "curModel": modelID, "collapseTime": 1200,
+	}
+
+	values := []interface{}{
+		collectionConf,
+		map[string]interface{}{strconv.FormatInt(modelID, 10): noteType},
+		map[string]interface{}{"1": deck(1, "Default", ""), strconv.FormatInt(deckID, 10): deck(deckID, cardSet.Title, cardSet.Description)},
+		map[string]interface{}{"1": deckConf},
+	}
+	encoded := make([]string, len(values))
+	for i, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		encoded[i] = string(data)
+	}
+
+	return encoded[0], encoded[1], encoded[2], encoded[3], nil
+}
+
+// ankiFieldHTML escapes plain card text for an Anki field
+func ankiFieldHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// ankiGUID derives a stable note guid from the card id so re-imports update existing notes
+func ankiGUID(cardID string) string {
+	id, err := uuid.Parse(cardID)
+	if err != nil {
+		sum := sha1.Sum([]byte(cardID))
+		return hex.EncodeToString(sum[:5])
+	}
+	return hex.EncodeToString(id[:5])
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"learn-backend/models"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Export formats
+const (
+	ExportFormatCSV = "csv"
+	ExportFormatTSV = "tsv"
+)
+
+// CardColumns are the CardSetCard fields that can be mapped to import/export columns, in export order
+var CardColumns = []string{"terminology", "define", "example", "part_of_speech", "phonetic", "image_url"}
+
+// cardColumnLimits mirrors the max lengths of the CardSetCard binding tags
+var cardColumnLimits = map[string]int{
+	"terminology":    500,
+	"define":         2000,
+	"example":        1000,
+	"part_of_speech": 50,
+	"phonetic":       100,
+	"image_url":      500,
+}
+
+// ImportOptions describes how pasted or uploaded text is split into cards
+type ImportOptions struct {
+	TermDelimiter string   // between the columns of a row
+	RowDelimiter  string   // between rows
+	Columns       []string // column order, defaults to terminology,define
+	HasHeader     bool     // first row names the columns
+}
+
+type CardImportService struct{}
+
+func NewCardImportService() *CardImportService {
+	return &CardImportService{}
+}
+
+// ParseDelimiter turns a delimiter option into the literal separator.
+// Named values cover the choices of the usual "paste your cards" dialogs.
+func ParseDelimiter(value, defaultValue string) string {
+	switch strings.ToLower(value) {
+	case "":
+		return defaultValue
+	case "tab", "\\t":
+		return "\t"
+	case "comma":
+		return ","
+	case "semicolon":
+		return ";"
+	case "dash":
+		return " - "
+	case "newline", "\\n":
+		return "\n"
+	}
+	return value
+}
+
+// ParseColumns validates a comma separated column list
+func ParseColumns(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return []string{"terminology", "define"}, nil
+	}
+
+	columns := []string{}
+	for _, column := range strings.Split(value, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if _, ok := cardColumnLimits[column]; !ok && column != "" && column != "-" {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// Parse splits text into cards. Rows that fail validation are reported in the
+// returned errors (1-based row numbers) and left out of the cards.
+func (s *CardImportService) Parse(text string, opts ImportOptions) ([]models.CardSetCard, []models.ImportRowError) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	rows, err := s.splitRows(text, opts)
+	if err != nil {
+		return nil, []models.ImportRowError{{Row: 0, Error: err.Error()}}
+	}
+
+	columns := opts.Columns
+	if opts.HasHeader && len(rows) > 0 {
+		header, err := ParseColumns(strings.Join(rows[0].fields, ","))
+		if err != nil {
+			return nil, []models.ImportRowError{{Row: rows[0].number, Error: err.Error()}}
+		}
+		columns = header
+		rows = rows[1:]
+	}
+
+	cards := []models.CardSetCard{}
+	rowErrors := []models.ImportRowError{}
+	for _, row := range rows {
+		if isBlankRow(row.fields) {
+			continue
+		}
+
+		card, err := cardFromFields(row.fields, columns)
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row.number, Error: err.Error()})
+			continue
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rowErrors
+}
+
+// importRow is one parsed row with its 1-based row number in the source text
+type importRow struct {
+	number int
+	fields []string
+}
+
+// splitRows uses the CSV reader (quotes, embedded newlines) when the delimiters allow it
+// and falls back to plain string splitting for multi-character delimiters
+func (s *CardImportService) splitRows(text string, opts ImportOptions) ([]importRow, error) {
+	termDelimiter := opts.TermDelimiter
+	if termDelimiter == "" {
+		termDelimiter = "\t"
+	}
+	rowDelimiter := opts.RowDelimiter
+	if rowDelimiter == "" {
+		rowDelimiter = "\n"
+	}
+
+	if rowDelimiter == "\n" && utf8.RuneCountInString(termDelimiter) == 1 && termDelimiter != "\"" {
+		reader := csv.NewReader(strings.NewReader(text))
+		reader.Comma, _ = utf8.DecodeRuneInString(termDelimiter)
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+
+		rows := []importRow{}
+		for {
+			fields, err := reader.Read()
+			if err == io.EOF {
+				return rows, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			line, _ := reader.FieldPos(0)
+			rows = append(rows, importRow{number: line, fields: fields})
+		}
+	}
+
+	// Keep the remainder in the last column so definitions may contain the delimiter
+	limit := len(opts.Columns)
+	if opts.HasHeader || limit == 0 {
+		limit = -1
+	}
+
+	rows := []importRow{}
+	for i, line := range strings.Split(text, rowDelimiter) {
+		rows = append(rows, importRow{number: i + 1, fields: strings.SplitN(line, termDelimiter, limit)})
+	}
+	return rows, nil
+}
+
+func isBlankRow(fields []string) bool {
+	for _, field := range fields {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func cardFromFields(fields, columns []string) (models.CardSetCard, error) {
+	card := models.CardSetCard{ID: uuid.New().String()}
+
+	if len(fields) > len(columns) {
+		return card, fmt.Errorf("expected at most %d columns, got %d", len(columns), len(fields))
+	}
+
+	for i, field := range fields {
+		column := columns[i]
+		if column == "" || column == "-" {
+			continue
+		}
+
+		value := strings.TrimSpace(field)
+		if limit := cardColumnLimits[column]; utf8.RuneCountInString(value) > limit {
+			return card, fmt.Errorf("%s is longer than %d characters", column, limit)
+		}
+
+		switch column {
+		case "terminology":
+			card.Terminology = value
+		case "define":
+			card.Define = value
+		case "example":
+			card.Example = value
+		case "part_of_speech":
+			card.PartOfSpeech = value
+		case "phonetic":
+			card.Phonetic = value
+		case "image_url":
+			card.ImageURL = value
+		}
+	}
+
+	if card.Terminology == "" {
+		return card, fmt.Errorf("terminology is required")
+	}
+	if card.Define == "" {
+		return card, fmt.Errorf("define is required")
+	}
+
+	return card, nil
+}
+
+// Export writes the cards as CSV or TSV with a header row naming the columns
+func (s *CardImportService) Export(w io.Writer, cards []models.CardSetCard, format string) error {
+	writer := csv.NewWriter(w)
+	if format == ExportFormatTSV {
+		writer.Comma = '\t'
+	}
+
+	if err := writer.Write(CardColumns); err != nil {
+		return err
+	}
+	for _, card := range cards {
+		record := []string{card.Terminology, card.Define, card.Example, card.PartOfSpeech, card.Phonetic, card.ImageURL}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportFilename builds a download file name from the card set title
+func ExportFilename(title, format string) string {
+	var b bytes.Buffer
+	for _, r := range strings.TrimSpace(title) {
+		switch {
+		case r == ' ' || r == '-' || r == '_':
+			if b.Len() > 0 && !bytes.HasSuffix(b.Bytes(), []byte("_")) {
+				b.WriteRune('_')
+			}
+		case r < utf8.RuneSelf && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'):
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("cardset")
+	}
+	return b.String() + "." + format
+}
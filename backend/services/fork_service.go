@@ -0,0 +1,166 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"learn-backend/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ForkService compares forked card sets with their source and merges the source changes
+// into the fork. Cards are matched through the fork's source card mapping and compared by
+// content hash, so edits on either side are detected without keeping the base cards.
+type ForkService struct{}
+
+func NewForkService() *ForkService {
+	return &ForkService{}
+}
+
+// CardHash fingerprints the content of a card, ignoring its id
+func CardHash(card models.CardSetCard) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		card.Terminology,
+		card.Define,
+		card.Example,
+		card.ImageURL,
+		card.PartOfSpeech,
+		card.Phonetic,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NewForkOrigin records the lineage of a copy of source, where cards[i] is the copy of source.Cards[i]
+func NewForkOrigin(source models.CardSet, cards []models.CardSetCard) *models.ForkOrigin {
+	forked := make([]models.ForkedCard, len(cards))
+	for i, card := range cards {
+		forked[i] = models.ForkedCard{
+			ID:       card.ID,
+			SourceID: source.Cards[i].ID,
+			Hash:     CardHash(source.Cards[i]),
+		}
+	}
+
+	return &models.ForkOrigin{
+		CardSetID: source.ID,
+		Revision:  source.Version,
+		SyncedAt:  time.Now(),
+		Cards:     forked,
+	}
+}
+
+// Diff lists the changes of upstream since fork was last synced
+func (s *ForkService) Diff(fork, upstream models.CardSet) models.UpstreamDiff {
+	_, _, diff := s.merge(fork, upstream)
+	return diff
+}
+
+// Merge applies the upstream changes to the cards of fork and returns them with the updated origin.
+// Cards edited or removed locally are left alone.
+func (s *ForkService) Merge(fork, upstream models.CardSet) ([]models.CardSetCard, *models.ForkOrigin, models.UpstreamDiff) {
+	return s.merge(fork, upstream)
+}
+
+func (s *ForkService) merge(fork, upstream models.CardSet) ([]models.CardSetCard, *models.ForkOrigin, models.UpstreamDiff) {
+	diff := models.UpstreamDiff{
+		UpstreamID:       upstream.ID,
+		BaseRevision:     fork.ForkedFrom.Revision,
+		UpstreamRevision: upstream.Version,
+		Added:            []models.CardSetCard{},
+		Changed:          []models.UpstreamCardChange{},
+		Removed:          []models.CardSetCard{},
+		Conflicts:        []models.UpstreamCardChange{},
+	}
+
+	localIndex := make(map[string]int, len(fork.Cards))
+	for i, card := range fork.Cards {
+		localIndex[card.ID] = i
+	}
+	bySource := make(map[string]models.ForkedCard, len(fork.ForkedFrom.Cards))
+	for _, forked := range fork.ForkedFrom.Cards {
+		bySource[forked.SourceID] = forked
+	}
+
+	inUpstream := make(map[string]bool, len(upstream.Cards))
+	replaced := map[string]models.CardSetCard{}
+	removed := map[string]bool{}
+	mapping := make([]models.ForkedCard, 0, len(upstream.Cards))
+
+	for _, source := range upstream.Cards {
+		inUpstream[source.ID] = true
+		hash := CardHash(source)
+
+		forked, ok := bySource[source.ID]
+		if !ok {
+			card := source
+			card.ID = uuid.New().String()
+			diff.Added = append(diff.Added, card)
+			mapping = append(mapping, models.ForkedCard{ID: card.ID, SourceID: source.ID, Hash: hash})
+			continue
+		}
+
+		// Cards removed from the fork stay mapped, so they aren't added back
+		mapping = append(mapping, models.ForkedCard{ID: forked.ID, SourceID: source.ID, Hash: hash})
+		i, ok := localIndex[forked.ID]
+		if !ok {
+			continue
+		}
+
+		local := fork.Cards[i]
+		localHash := CardHash(local)
+		switch {
+		case hash == forked.Hash || hash == localHash:
+			// Unchanged upstream, or the same edit on both sides
+		case localHash == forked.Hash:
+			updated := source
+			updated.ID = local.ID
+			diff.Changed = append(diff.Changed, models.UpstreamCardChange{Local: local, Upstream: &updated})
+			replaced[local.ID] = updated
+		default:
+			upstreamCard := source
+			diff.Conflicts = append(diff.Conflicts, models.UpstreamCardChange{Local: local, Upstream: &upstreamCard})
+		}
+	}
+
+	// Source cards that are gone upstream. Locally edited ones become plain local cards.
+	for _, forked := range fork.ForkedFrom.Cards {
+		if inUpstream[forked.SourceID] {
+			continue
+		}
+		i, ok := localIndex[forked.ID]
+		if !ok {
+			continue
+		}
+
+		local := fork.Cards[i]
+		if CardHash(local) == forked.Hash {
+			diff.Removed = append(diff.Removed, local)
+			removed[local.ID] = true
+		} else {
+			diff.Conflicts = append(diff.Conflicts, models.UpstreamCardChange{Local: local})
+		}
+	}
+
+	cards := make([]models.CardSetCard, 0, len(fork.Cards)+len(diff.Added))
+	for _, card := range fork.Cards {
+		if removed[card.ID] {
+			continue
+		}
+		if updated, ok := replaced[card.ID]; ok {
+			card = updated
+		}
+		cards = append(cards, card)
+	}
+	cards = append(cards, diff.Added...)
+
+	origin := &models.ForkOrigin{
+		CardSetID: upstream.ID,
+		Revision:  upstream.Version,
+		SyncedAt:  time.Now(),
+		Cards:     mapping,
+	}
+
+	return cards, origin, diff
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// GradeVerdict constants
+const (
+	VerdictCorrect   = "correct" // matches after normalization
+	VerdictAlmost    = "almost"  // accepted with a small typo
+	VerdictIncorrect = "incorrect"
+)
+
+// DiffOp constants
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert" // missing from the answer
+	DiffDelete = "delete" // extra in the answer
+)
+
+// Longer answers get a plain delete/insert diff, so the diff table stays small
+const maxDiffCells = 250000
+
+// Leading articles ignored when comparing answers
+var gradingArticles = []string{"a", "an", "the"}
+
+// DiffSegment is one run of a character-level diff between an answer and the expected text
+type DiffSegment struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// GradeResult is the outcome of checking an answer
+type GradeResult struct {
+	Verdict       string        `json:"verdict"`
+	Correct       bool          `json:"correct"`
+	MatchedAnswer string        `json:"matched_answer"` // the accepted alternative closest to the answer
+	Distance      int           `json:"distance"`       // edit distance after normalization
+	Diff          []DiffSegment `json:"diff"`
+}
+
+type GradingService struct{}
+
+func NewGradingService() *GradingService {
+	return &GradingService{}
+}
+
+// Check compares an answer with the expected text. The expected text may hold several
+// alternatives separated by "/" or ";" and optional parts in parentheses.
+func (gs *GradingService) Check(answer, expected string) GradeResult {
+	normalizedAnswer := normalizeAnswer(answer)
+
+	best := GradeResult{Verdict: VerdictIncorrect, Distance: -1}
+	for _, alternative := range splitAlternatives(expected) {
+		for _, variant := range answerVariants(alternative) {
+			normalized := normalizeAnswer(variant)
+			if normalized == "" {
+				continue
+			}
+
+			distance := levenshtein([]rune(normalizedAnswer), []rune(normalized))
+			if best.Distance == -1 || distance < best.Distance {
+				best.Distance = distance
+				best.MatchedAnswer = alternative
+				best.Verdict = VerdictIncorrect
+				if normalizedAnswer != "" {
+					if distance == 0 {
+						best.Verdict = VerdictCorrect
+					} else if distance <= typoTolerance(len([]rune(normalized))) {
+						best.Verdict = VerdictAlmost
+					}
+				}
+			}
+		}
+	}
+
+	if best.Distance == -1 {
+		best.Distance = len([]rune(normalizedAnswer))
+		best.MatchedAnswer = strings.TrimSpace(expected)
+	}
+
+	best.Correct = best.Verdict != VerdictIncorrect
+	best.Diff = diffAnswer(norm.NFC.String(strings.TrimSpace(answer)), norm.NFC.String(best.MatchedAnswer))
+	return best
+}
+
+// IsCorrect reports whether the answer is accepted
+func (gs *GradingService) IsCorrect(answer, expected string) bool {
+	return gs.Check(answer, expected).Correct
+}
+
+// splitAlternatives splits "run / sprint; dash" into its alternatives
+func splitAlternatives(expected string) []string {
+	parts := strings.FieldsFunc(expected, func(r rune) bool { return r == '/' || r == ';' })
+	alternatives := []string{}
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			alternatives = append(alternatives, part)
+		}
+	}
+	return alternatives
+}
+
+// answerVariants returns the alternative with and without its parenthetical parts
+func answerVariants(alternative string) []string {
+	withParts := strings.NewReplacer("(", "", ")", "").Replace(alternative)
+
+	var without strings.Builder
+	depth := 0
+	for _, r := range alternative {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0:
+			without.WriteRune(r)
+		}
+	}
+
+	if withParts == without.String() {
+		return []string{withParts}
+	}
+	return []string{withParts, without.String()}
+}
+
+// normalizeAnswer folds case and diacritics, drops punctuation and leading articles
+func normalizeAnswer(s string) string {
+	// Decompose the whole string so input typed as base letters plus combining marks folds the same
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if r == 'đ' {
+			r = 'd'
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(b.String())
+	if len(words) > 1 {
+		for _, article := range gradingArticles {
+			if words[0] == article {
+				words = words[1:]
+				break
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// foldRune lowercases a rune and strips its diacritics ("Ế" -> "e", "đ" -> "d")
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if r == 'đ' {
+		return 'd'
+	}
+	if r < unicode.MaxASCII {
+		return r
+	}
+	for _, base := range norm.NFD.String(string(r)) {
+		return base
+	}
+	return r
+}
+
+// typoTolerance is the number of edits accepted for an expected answer of the given length
+func typoTolerance(length int) int {
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 6:
+		return 1
+	case length <= 12:
+		return 2
+	default:
+		return length * 15 / 100
+	}
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// diffAnswer builds a character-level diff turning the answer into the expected text.
+// Characters are compared case and diacritic insensitively.
+func diffAnswer(answer, expected string) []DiffSegment {
+	a := []rune(answer)
+	b := []rune(expected)
+
+	// Only the part between a common prefix and suffix needs the diff table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && foldRune(a[prefix]) == foldRune(b[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && foldRune(a[len(a)-1-suffix]) == foldRune(b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	segments := []DiffSegment{}
+	if prefix > 0 {
+		segments = append(segments, DiffSegment{Op: DiffEqual, Text: string(b[:prefix])})
+	}
+	segments = append(segments, diffRunes(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	if suffix > 0 {
+		segments = append(segments, DiffSegment{Op: DiffEqual, Text: string(b[len(b)-suffix:])})
+	}
+	return segments
+}
+
+// diffRunes diffs two runs of runes that differ at their first and last rune
+func diffRunes(a, b []rune) []DiffSegment {
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		segments := []DiffSegment{}
+		if len(a) > 0 {
+			segments = append(segments, DiffSegment{Op: DiffDelete, Text: string(a)})
+		}
+		if len(b) > 0 {
+			segments = append(segments, DiffSegment{Op: DiffInsert, Text: string(b)})
+		}
+		return segments
+	}
+
+	// dist[i][j] is the edit distance between a[i:] and b[j:]
+	dist := make([][]int, len(a)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(b)+1)
+	}
+	for i := len(a); i >= 0; i-- {
+		for j := len(b); j >= 0; j-- {
+			switch {
+			case i == len(a):
+				dist[i][j] = len(b) - j
+			case j == len(b):
+				dist[i][j] = len(a) - i
+			case foldRune(a[i]) == foldRune(b[j]):
+				dist[i][j] = dist[i+1][j+1]
+			default:
+				dist[i][j] = 1 + min(dist[i+1][j], dist[i][j+1], dist[i+1][j+1])
+			}
+		}
+	}
+
+	// Consecutive deletions and insertions are grouped so substitutions read as "delete X, insert Y"
+	segments := []DiffSegment{}
+	var deleted, inserted []rune
+	flush := func() {
+		if len(deleted) > 0 {
+			segments = append(segments, DiffSegment{Op: DiffDelete, Text: string(deleted)})
+			deleted = nil
+		}
+		if len(inserted) > 0 {
+			segments = append(segments, DiffSegment{Op: DiffInsert, Text: string(inserted)})
+			inserted = nil
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && foldRune(a[i]) == foldRune(b[j]):
+			flush()
+			if n := len(segments); n > 0 && segments[n-1].Op == DiffEqual {
+				segments[n-1].Text += string(b[j])
+			} else {
+				segments = append(segments, DiffSegment{Op: DiffEqual, Text: string(b[j])})
+			}
+			i++
+			j++
+		case i < len(a) && j < len(b) && dist[i][j] == 1+dist[i+1][j+1]:
+			// Substitution
+			deleted = append(deleted, a[i])
+			inserted = append(inserted, b[j])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || dist[i][j] == 1+dist[i+1][j]):
+			deleted = append(deleted, a[i])
+			i++
+		default:
+			inserted = append(inserted, b[j])
+			j++
+		}
+	}
+	flush()
+
+	return segments
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		answer   string
+		expected string
+		verdict  string
+		matched  string
+	}{
+		{"exact", "house", "house", VerdictCorrect, "house"},
+		{"case and punctuation", "  House! ", "house", VerdictCorrect, "house"},
+		{"diacritics are ignored", "tot", "tốt", VerdictCorrect, "tốt"},
+		{"d with stroke", "Dung", "đúng", VerdictCorrect, "đúng"},
+		{"decomposed answer", norm.NFD.String("tốt"), "tốt", VerdictCorrect, "tốt"},
+		{"decomposed answer with two words", norm.NFD.String("tiếng Việt"), "tiếng Việt", VerdictCorrect, "tiếng Việt"},
+		{"decomposed expected text", "tiếng Việt", norm.NFD.String("tiếng Việt"), VerdictCorrect, norm.NFD.String("tiếng Việt")},
+		{"second alternative", "sprint", "run / sprint; dash", VerdictCorrect, "sprint"},
+		{"third alternative", "dash", "run / sprint; dash", VerdictCorrect, "dash"},
+		{"no alternative matches", "walk", "run / sprint", VerdictIncorrect, "run"},
+		{"parenthetical left out", "bank", "bank (of a river)", VerdictCorrect, "bank (of a river)"},
+		{"parenthetical included", "bank of a river", "bank (of a river)", VerdictCorrect, "bank (of a river)"},
+		{"leading article in the answer", "the house", "house", VerdictCorrect, "house"},
+		{"leading article in the expected text", "apple", "an apple", VerdictCorrect, "an apple"},
+		{"single word article is kept", "a", "a", VerdictCorrect, "a"},
+		{"short answers have no tolerance", "cap", "cat", VerdictIncorrect, "cat"},
+		{"one typo in a medium word", "hous", "house", VerdictAlmost, "house"},
+		{"missing letter in a medium word", "hose", "horse", VerdictAlmost, "horse"},
+		{"too many typos", "hxxse", "house", VerdictIncorrect, "house"},
+		{"two typos in a long word", "elefant", "elephant", VerdictAlmost, "elephant"},
+		{"empty answer", "", "house", VerdictIncorrect, "house"},
+		{"empty expected text", "house", " ", VerdictIncorrect, ""},
+	}
+
+	gs := NewGradingService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := gs.Check(tt.answer, tt.expected)
+			if result.Verdict != tt.verdict {
+				t.Errorf("Check(%q, %q).Verdict = %q, want %q", tt.answer, tt.expected, result.Verdict, tt.verdict)
+			}
+			if result.Correct != (tt.verdict != VerdictIncorrect) {
+				t.Errorf("Check(%q, %q).Correct = %v with verdict %q", tt.answer, tt.expected, result.Correct, result.Verdict)
+			}
+			if result.MatchedAnswer != tt.matched {
+				t.Errorf("Check(%q, %q).MatchedAnswer = %q, want %q", tt.answer, tt.expected, result.MatchedAnswer, tt.matched)
+			}
+		})
+	}
+}
+
+func TestTypoTolerance(t *testing.T) {
+	tests := []struct {
+		length int
+		want   int
+	}{
+		{1, 0},
+		{3, 0},
+		{4, 1},
+		{6, 1},
+		{7, 2},
+		{12, 2},
+		{20, 3},
+		{40, 6},
+	}
+
+	for _, tt := range tests {
+		if got := typoTolerance(tt.length); got != tt.want {
+			t.Errorf("typoTolerance(%d) = %d, want %d", tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestDiffAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		answer   string
+		expected string
+		want     []DiffSegment
+	}{
+		{"equal", "house", "house", []DiffSegment{{DiffEqual, "house"}}},
+		{"equal ignoring case and diacritics", "Tot", "tốt", []DiffSegment{{DiffEqual, "tốt"}}},
+		{"missing letter", "hous", "house", []DiffSegment{{DiffEqual, "hous"}, {DiffInsert, "e"}}},
+		{"extra letter", "housse", "house", []DiffSegment{{DiffEqual, "hous"}, {DiffDelete, "s"}, {DiffEqual, "e"}}},
+		{"substitution", "hoose", "house", []DiffSegment{{DiffEqual, "ho"}, {DiffDelete, "o"}, {DiffInsert, "u"}, {DiffEqual, "se"}}},
+		{"empty answer", "", "house", []DiffSegment{{DiffInsert, "house"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffAnswer(tt.answer, tt.expected)
+			if !equalSegments(got, tt.want) {
+				t.Errorf("diffAnswer(%q, %q) = %v, want %v", tt.answer, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffAnswerLongTexts(t *testing.T) {
+	answer := strings.Repeat("a", 2000)
+	expected := strings.Repeat("b", 2000)
+
+	want := []DiffSegment{{DiffDelete, answer}, {DiffInsert, expected}}
+	if got := diffAnswer(answer, expected); !equalSegments(got, want) {
+		t.Errorf("diffAnswer of long texts = %d segments, want a delete and an insert", len(got))
+	}
+
+	// A long common prefix is kept out of the diff table
+	prefix := strings.Repeat("x", 2000)
+	want = []DiffSegment{{DiffEqual, prefix + "hous"}, {DiffInsert, "e"}}
+	if got := diffAnswer(prefix+"hous", prefix+"house"); !equalSegments(got, want) {
+		t.Errorf("diffAnswer with a long common prefix = %v, want %v", got, want)
+	}
+}
+
+func equalSegments(a, b []DiffSegment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"learn-backend/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLearnRoundSize = 7
+	learnChoiceCount      = 4
+)
+
+// LearnService is shared by concurrent requests; it draws from the
+// package-level math/rand/v2 source, which is safe for concurrent use
+type LearnService struct {
+	grader *GradingService
+}
+
+func NewLearnService() *LearnService {
+	return &LearnService{
+		grader: NewGradingService(),
+	}
+}
+
+// NewSession initializes the per-card state and the first round of a learn session
+func (ls *LearnService) NewSession(session *models.LearnSession, cards []models.CardSetCard) {
+	session.Cards = make([]models.LearnCardState, 0, len(cards))
+	for _, card := range cards {
+		session.Cards = append(session.Cards, models.LearnCardState{
+			CardID: card.ID,
+			Stage:  models.LearnStageNew,
+		})
+	}
+	ls.startRound(session)
+}
+
+// startRound picks the cards of the next round, favouring the ones answered wrong most often
+func (ls *LearnService) startRound(session *models.LearnSession) {
+	pending := []int{}
+	for i := range session.Cards {
+		session.Cards[i].Answered = false
+		if session.Cards[i].Stage < models.LearnStageMastered {
+			pending = append(pending, i)
+		}
+	}
+
+	rand.Shuffle(len(pending), func(i, j int) { pending[i], pending[j] = pending[j], pending[i] })
+	// Stable order by number of mistakes (insertion sort keeps the shuffle for ties)
+	for i := 1; i < len(pending); i++ {
+		for j := i; j > 0 && session.Cards[pending[j]].Incorrect > session.Cards[pending[j-1]].Incorrect; j-- {
+			pending[j], pending[j-1] = pending[j-1], pending[j]
+		}
+	}
+
+	if len(pending) > session.RoundSize {
+		pending = pending[:session.RoundSize]
+	}
+
+	session.Round++
+	session.RoundCardIDs = make([]string, 0, len(pending))
+	for _, i := range pending {
+		session.RoundCardIDs = append(session.RoundCardIDs, session.Cards[i].CardID)
+	}
+}
+
+// NextQuestion returns the question to ask next, or nil when every card is mastered
+func (ls *LearnService) NextQuestion(session *models.LearnSession, cards []models.CardSetCard) *models.LearnQuestion {
+	if session.CurrentQuestion != nil {
+		return session.CurrentQuestion
+	}
+
+	state := ls.nextCard(session)
+	if state == nil {
+		ls.startRound(session)
+		state = ls.nextCard(session)
+		if state == nil {
+			return nil
+		}
+	}
+
+	var card *models.CardSetCard
+	for i := range cards {
+		if cards[i].ID == state.CardID {
+			card = &cards[i]
+			break
+		}
+	}
+	if card == nil {
+		// The card was removed from the set since the session started
+		state.Stage = models.LearnStageMastered
+		state.Answered = true
+		return ls.NextQuestion(session, cards)
+	}
+
+	question := ls.buildQuestion(session, state, *card, cards)
+	session.CurrentQuestion = question
+	return question
+}
+
+// nextCard returns the first card of the current round not answered yet
+func (ls *LearnService) nextCard(session *models.LearnSession) *models.LearnCardState {
+	for _, cardID := range session.RoundCardIDs {
+		for i := range session.Cards {
+			state := &session.Cards[i]
+			if state.CardID == cardID && !state.Answered && state.Stage < models.LearnStageMastered {
+				return state
+			}
+		}
+	}
+	return nil
+}
+
+func (ls *LearnService) buildQuestion(session *models.LearnSession, state *models.LearnCardState, card models.CardSetCard, cards []models.CardSetCard) *models.LearnQuestion {
+	prompt, answer := sides(card, session.AnswerWith)
+
+	question := &models.LearnQuestion{
+		ID:      uuid.New().String(),
+		CardID:  card.ID,
+		Prompt:  prompt,
+		Answer:  answer,
+		AskedAt: time.Now(),
+	}
+
+	distractors := pickDistractors(card, cards, session.AnswerWith, learnChoiceCount-1)
+
+	switch {
+	case state.Stage >= models.LearnStageRecognized:
+		question.Type = models.QuestionTypeWritten
+	case len(distractors) == 0 || rand.IntN(4) == 0:
+		// True/false also covers sets too small for multiple choice
+		question.Type = models.QuestionTypeTrueFalse
+		question.Statement = answer
+		if len(distractors) > 0 && rand.IntN(2) == 0 {
+			question.Statement = distractors[0]
+		}
+		if question.Statement == answer {
+			question.Answer = "true"
+		} else {
+			question.Answer = "false"
+		}
+		question.Options = []string{"true", "false"}
+	default:
+		question.Type = models.QuestionTypeMultipleChoice
+		options := append([]string{answer}, distractors...)
+		rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+		question.Options = options
+	}
+
+	return question
+}
+
+// pickDistractors returns up to n wrong answers taken from other cards of the set
+func pickDistractors(card models.CardSetCard, cards []models.CardSetCard, answerWith string, n int) []string {
+	_, answer := sides(card, answerWith)
+
+	candidates := []string{}
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(answer)): true}
+	for _, other := range cards {
+		if other.ID == card.ID {
+			continue
+		}
+		_, value := sides(other, answerWith)
+		key := strings.ToLower(strings.TrimSpace(value))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, value)
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// Grade checks an answer against the current question
+func (ls *LearnService) Grade(question *models.LearnQuestion, answer string) bool {
+	switch question.Type {
+	case models.QuestionTypeWritten:
+		return ls.grader.IsCorrect(answer, question.Answer)
+	default:
+		// Card sides may carry stray whitespace, so both are trimmed
+		return strings.TrimSpace(answer) == strings.TrimSpace(question.Answer)
+	}
+}
+
+// ApplyAnswer moves the card between stages and closes the current question.
+// It returns true when every card of the session is mastered.
+func (ls *LearnService) ApplyAnswer(session *models.LearnSession, correct bool) bool {
+	question := session.CurrentQuestion
+	session.CurrentQuestion = nil
+
+	for i := range session.Cards {
+		state := &session.Cards[i]
+		if state.CardID != question.CardID {
+			continue
+		}
+		state.Answered = true
+		if correct {
+			state.Correct++
+			state.Stage++
+		} else {
+			state.Incorrect++
+			if state.Stage > models.LearnStageNew {
+				state.Stage--
+			}
+		}
+		break
+	}
+
+	for _, state := range session.Cards {
+		if state.Stage < models.LearnStageMastered {
+			return false
+		}
+	}
+	return true
+}
+
+// sides returns the prompt and the expected answer of a card
+func sides(card models.CardSetCard, answerWith string) (string, string) {
+	if answerWith == models.AnswerWithTerm {
+		return card.Define, card.Terminology
+	}
+	return card.Terminology, card.Define
+}
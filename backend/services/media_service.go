@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxMediaSize caps a single stored or downloaded media file
+const MaxMediaSize = 2 << 20
+
+// ErrUnsupportedMedia is returned for files that are not images or are too large
+var ErrUnsupportedMedia = errors.New("unsupported media file")
+
+// ErrForbiddenMediaHost is returned for media URLs pointing at internal addresses
+var ErrForbiddenMediaHost = errors.New("media host not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't reachable from the internet either
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// MediaFile is an image held in memory
+type MediaFile struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// MediaService stores card images in the "media" GridFS bucket and
+// serves them from a public URL under PublicURL
+type MediaService struct {
+	db        *mongo.Database
+	publicURL string
+	client    *http.Client
+}
+
+func NewMediaService(db *mongo.Database, publicURL string) *MediaService {
+	return &MediaService{
+		db:        db,
+		publicURL: publicURL,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			// Every connection, including redirects, is checked after DNS resolution,
+			// so rebinding a host to an internal address doesn't get through
+			Transport: &http.Transport{
+				Proxy: nil,
+				DialContext: (&net.Dialer{
+					Timeout: 5 * time.Second,
+					Control: func(network, address string, _ syscall.RawConn) error {
+						addrPort, err := netip.ParseAddrPort(address)
+						if err != nil || !isPublicAddr(addrPort.Addr()) {
+							return ErrForbiddenMediaHost
+						}
+						return nil
+					},
+				}).DialContext,
+				TLSHandshakeTimeout:   5 * time.Second,
+				ResponseHeaderTimeout: 5 * time.Second,
+			},
+		},
+	}
+}
+
+func (s *MediaService) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(s.db, options.GridFSBucket().SetName("media"))
+}
+
+// URL returns the public URL of a stored media file
+func (s *MediaService) URL(id primitive.ObjectID) string {
+	return s.publicURL + "/api/v1/media/" + id.Hex()
+}
+
+// Save stores an image owned by the user and returns its id
+func (s *MediaService) Save(ctx context.Context, userID primitive.ObjectID, file MediaFile) (primitive.ObjectID, error) {
+	contentType := http.DetectContentType(file.Data)
+	if len(file.Data) > MaxMediaSize || !strings.HasPrefix(contentType, "image/") {
+		return primitive.NilObjectID, ErrUnsupportedMedia
+	}
+
+	bucket, err := s.bucket()
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		bucket.SetWriteDeadline(deadline)
+	}
+
+	return bucket.UploadFromStream(file.Filename, bytes.NewReader(file.Data), options.GridFSUpload().SetMetadata(bson.M{
+		"user_id":      userID,
+		"content_type": contentType,
+	}))
+}
+
+// Open loads a stored media file
+func (s *MediaService) Open(ctx context.Context, id primitive.ObjectID) (*MediaFile, error) {
+	bucket, err := s.bucket()
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		bucket.SetReadDeadline(deadline)
+	}
+
+	stream, err := bucket.OpenDownloadStream(id)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata struct {
+		ContentType string `bson:"content_type"`
+	}
+	if raw := stream.GetFile().Metadata; raw != nil {
+		_ = bson.Unmarshal(raw, &metadata)
+	}
+	if metadata.ContentType == "" {
+		metadata.ContentType = http.DetectContentType(data)
+	}
+
+	return &MediaFile{Filename: stream.GetFile().Name, ContentType: metadata.ContentType, Data: data}, nil
+}
+
+// Fetch loads an image by URL, reading our own media URLs from the bucket
+// and downloading anything else over http(s)
+// Internal hosts (loopback, private, link-local and metadata addresses) are refused.
+func (s *MediaService) Fetch(ctx context.Context, rawURL string) (*MediaFile, error) {
+	if hex, ok := strings.CutPrefix(rawURL, s.publicURL+"/api/v1/media/"); ok {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return nil, ErrUnsupportedMedia
+		}
+		return s.Open(ctx, id)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return nil, ErrUnsupportedMedia
+	}
+
+	// Fail early on internal hosts; the dialer checks the address actually connected to
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", parsed.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return nil, ErrForbiddenMediaHost
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: status %d", rawURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxMediaSize+1))
+	if err != nil {
+		return nil, err
+	}
+	contentType := http.DetectContentType(data)
+	if len(data) > MaxMediaSize || !strings.HasPrefix(contentType, "image/") {
+		return nil, ErrUnsupportedMedia
+	}
+
+	name := parsed.Path[strings.LastIndex(parsed.Path, "/")+1:]
+	return &MediaFile{Filename: name, ContentType: contentType, Data: data}, nil
+}
+
+// isPublicAddr reports whether addr is a globally routable unicast address
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(addr)
+}
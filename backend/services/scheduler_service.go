@@ -0,0 +1,93 @@
+package services
+
+import (
+	"math"
+	"time"
+)
+
+// SM-2 defaults
+const (
+	DefaultEaseFactor = 2.5
+	MinEaseFactor     = 1.3
+)
+
+// ReviewState holds the spaced-repetition state of a single card
+type ReviewState struct {
+	EaseFactor   float64
+	IntervalDays int
+	Repetitions  int
+	Lapses       int
+	DueAt        time.Time
+}
+
+type SchedulerService struct{}
+
+func NewSchedulerService() *SchedulerService {
+	return &SchedulerService{}
+}
+
+// GradeFromAttempt converts a card attempt into an SM-2 quality (0-5).
+// ConfidenceLevel (1-5) is used when provided, otherwise only the correctness is known.
+func (ss *SchedulerService) GradeFromAttempt(correct bool, confidenceLevel int) int {
+	if confidenceLevel >= 1 && confidenceLevel <= 5 {
+		if !correct {
+			// A wrong answer is always a lapse, whatever the confidence
+			if confidenceLevel > 2 {
+				return 2
+			}
+			return confidenceLevel - 1
+		}
+		if confidenceLevel < 3 {
+			return 3
+		}
+		return confidenceLevel
+	}
+
+	if correct {
+		return 4
+	}
+	return 1
+}
+
+// Schedule applies one review with the given quality (0-5) and returns the new state
+func (ss *SchedulerService) Schedule(state ReviewState, quality int, reviewedAt time.Time) ReviewState {
+	if quality < 0 {
+		quality = 0
+	}
+	if quality > 5 {
+		quality = 5
+	}
+
+	next := state
+	if next.EaseFactor == 0 {
+		next.EaseFactor = DefaultEaseFactor
+	}
+
+	if quality < 3 {
+		// Failed recall: start over, card comes back tomorrow
+		if state.Repetitions > 0 {
+			next.Lapses++
+		}
+		next.Repetitions = 0
+		next.IntervalDays = 1
+	} else {
+		switch next.Repetitions {
+		case 0:
+			next.IntervalDays = 1
+		case 1:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(next.IntervalDays) * next.EaseFactor))
+		}
+		next.Repetitions++
+	}
+
+	q := float64(5 - quality)
+	next.EaseFactor = next.EaseFactor + (0.1 - q*(0.08+q*0.02))
+	if next.EaseFactor < MinEaseFactor {
+		next.EaseFactor = MinEaseFactor
+	}
+
+	next.DueAt = reviewedAt.AddDate(0, 0, next.IntervalDays)
+	return next
+}
@@ -0,0 +1,904 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"learn-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Statistics job queue tuning. Sessions waiting for their statistics carry a stats_job,
+// so recording a session and queueing its statistics update is a single write.
+const (
+	StatisticsPollInterval    = 2 * time.Second
+	StatisticsJobLease        = time.Minute // a claimed job is retried when not done by then
+	StatisticsJobMaxAttempts  = 10
+	statisticsJobBaseBackoff  = 5 * time.Second
+	statisticsJobMaxBackoff   = time.Hour
+	masteryUpdateRetries      = 5
+	masteryAppliedSessionsCap = 20
+)
+
+// StatisticsRebuildTimeout bounds the rebuild of a single user's statistics
+const StatisticsRebuildTimeout = 5 * time.Minute
+
+// Mastery levels, in percent, at which a card counts as learning and mastered
+const (
+	MasteryLevelLearning = 50
+	MasteryLevelMastered = 80
+)
+
+// Mastery buckets look at the latest answers to a card and its run of spaced recalls
+const (
+	MasteryRecentResults        = 10
+	masteryBucketReviewAccuracy = 50
+	masteryBucketMasterAccuracy = 80
+	masteryBucketMasterRecalls  = 3
+)
+
+// StatisticsService applies recorded study sessions to the card mastery and user statistics.
+// It works off the queue of sessions with a pending stats_job, retrying failed jobs with backoff.
+// Applying a session is idempotent, so a job that runs twice doesn't count the session twice.
+type StatisticsService struct {
+	db        *mongo.Database
+	streaks   *StreakService
+	scheduler *SchedulerService
+	wg        sync.WaitGroup
+}
+
+func NewStatisticsService(db *mongo.Database) *StatisticsService {
+	return &StatisticsService{
+		db:        db,
+		streaks:   NewStreakService(),
+		scheduler: NewSchedulerService(),
+	}
+}
+
+// Start works off the queue in the background until ctx is cancelled.
+// A job being applied at that point is finished; Wait blocks until it is.
+func (s *StatisticsService) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(StatisticsPollInterval)
+		defer ticker.Stop()
+
+		for {
+			// Drain the queue before waiting for the next tick
+			for ctx.Err() == nil {
+				processed, err := s.ProcessNext()
+				if err != nil {
+					log.Printf("Failed to process statistics job: %v", err)
+					break
+				}
+				if !processed {
+					break
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the background worker stopped
+func (s *StatisticsService) Wait() {
+	s.wg.Wait()
+}
+
+// ProcessNext claims the next due job and runs it: the statistics of a recorded session first,
+// then a queued recalculation of a user's statistics. It reports false when no job is due;
+// a failed job is rescheduled, not returned as error.
+func (s *StatisticsService) ProcessNext() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), StatisticsJobLease)
+	defer cancel()
+
+	var session models.StudySession
+	err := s.claimJob(ctx, "study_sessions", "stats_job", &session)
+	if err == nil {
+		applyErr := s.ApplySession(ctx, session)
+		return true, s.finishJob(ctx, "study_sessions", "stats_job", session.ID, *session.StatsJob, applyErr)
+	}
+	if err != mongo.ErrNoDocuments {
+		return false, err
+	}
+
+	var stats models.UserStatistics
+	err = s.claimJob(ctx, "user_statistics", "recalculate_job", &stats)
+	if err == nil {
+		recalculateErr := s.RecalculateUser(ctx, stats.UserID)
+		return true, s.finishJob(ctx, "user_statistics", "recalculate_job", stats.ID, *stats.RecalculateJob, recalculateErr)
+	}
+	if err != mongo.ErrNoDocuments {
+		return false, err
+	}
+	return false, nil
+}
+
+// claimJob claims the next due job kept in field of the collection's documents, decoding its document.
+// Claiming pushes run_at out by the lease, so a job whose worker died is picked up again.
+func (s *StatisticsService) claimJob(ctx context.Context, collection, field string, doc any) error {
+	now := time.Now()
+	return s.db.Collection(collection).FindOneAndUpdate(ctx,
+		bson.M{
+			field + ".run_at":   bson.M{"$lte": now},
+			field + ".attempts": bson.M{"$lt": StatisticsJobMaxAttempts},
+		},
+		bson.M{
+			"$set": bson.M{field + ".run_at": now.Add(StatisticsJobLease)},
+			"$inc": bson.M{field + ".attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: field + ".run_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(doc)
+}
+
+// finishJob removes a claimed job that succeeded, unless it was claimed again or requeued meanwhile.
+// A failed job is rescheduled with backoff until it used up its attempts, then marked failed.
+func (s *StatisticsService) finishJob(ctx context.Context, collection, field string, id primitive.ObjectID, job models.StatsJob, jobErr error) error {
+	if jobErr == nil {
+		_, err := s.db.Collection(collection).UpdateOne(ctx,
+			bson.M{"_id": id, field + ".run_at": job.RunAt},
+			bson.M{"$unset": bson.M{field: ""}},
+		)
+		return err
+	}
+
+	set := bson.M{field + ".last_error": jobErr.Error()}
+	if job.Attempts >= StatisticsJobMaxAttempts {
+		log.Printf("Giving up on %s %s after %d attempts: %v", field, id.Hex(), job.Attempts, jobErr)
+		set[field+".failed_at"] = time.Now()
+	} else {
+		backoff := min(statisticsJobBaseBackoff<<(job.Attempts-1), statisticsJobMaxBackoff)
+		set[field+".run_at"] = time.Now().Add(backoff)
+	}
+
+	_, err := s.db.Collection(collection).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// QueueRecalculation has the worker recalculate a user's statistics, e.g. after their timezone changed
+func (s *StatisticsService) QueueRecalculation(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.db.Collection("user_statistics").UpdateOne(ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": bson.M{"recalculate_job": models.NewStatsJob()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// failedJobsFilter matches the jobs that used up their attempts, including those whose
+// worker died during the last attempt and so never got a failed_at
+func failedJobsFilter(userID *primitive.ObjectID) bson.M {
+	filter := bson.M{"stats_job.attempts": bson.M{"$gte": StatisticsJobMaxAttempts}}
+	if userID != nil {
+		filter["user_id"] = *userID
+	}
+	return filter
+}
+
+// FailedJobs lists the sessions the worker gave up on, oldest first
+func (s *StatisticsService) FailedJobs(ctx context.Context, limit int64) ([]models.FailedStatisticsJob, error) {
+	cursor, err := s.db.Collection("study_sessions").Find(ctx, failedJobsFilter(nil),
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetLimit(limit).
+			SetProjection(bson.M{
+				"user_id":    1,
+				"cardset_id": 1,
+				"created_at": 1,
+				"attempts":   "$stats_job.attempts",
+				"last_error": "$stats_job.last_error",
+				"failed_at":  "$stats_job.failed_at",
+			}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []models.FailedStatisticsJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RetryFailedJobs requeues the failed jobs of a user, or of all users when userID is nil,
+// with a fresh set of attempts. It returns the number of requeued jobs.
+func (s *StatisticsService) RetryFailedJobs(ctx context.Context, userID *primitive.ObjectID) (int64, error) {
+	result, err := s.db.Collection("study_sessions").UpdateMany(ctx, failedJobsFilter(userID), bson.M{
+		"$set":   bson.M{"stats_job.attempts": 0, "stats_job.run_at": time.Now()},
+		"$unset": bson.M{"stats_job.failed_at": ""},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// ApplySession adds a session to the card mastery of its cards and recalculates the user statistics
+func (s *StatisticsService) ApplySession(ctx context.Context, session models.StudySession) error {
+	cardIDs, attemptsByCard := groupAttempts(session.Attempts)
+	for _, cardID := range cardIDs {
+		if err := s.applyCardAttempts(ctx, session, cardID, attemptsByCard[cardID]); err != nil {
+			return err
+		}
+	}
+
+	return s.RecalculateUser(ctx, session.UserID)
+}
+
+// groupAttempts groups a session's attempts by card, keeping their order, so each card is written once
+func groupAttempts(attempts []models.CardAttempt) ([]string, map[string][]models.CardAttempt) {
+	cardIDs := make([]string, 0)
+	attemptsByCard := make(map[string][]models.CardAttempt)
+	for _, attempt := range attempts {
+		if _, ok := attemptsByCard[attempt.CardID]; !ok {
+			cardIDs = append(cardIDs, attempt.CardID)
+		}
+		attemptsByCard[attempt.CardID] = append(attemptsByCard[attempt.CardID], attempt)
+	}
+	return cardIDs, attemptsByCard
+}
+
+// applyCardAttempts adds a session's attempts at one card to its mastery record.
+// The write is a compare-and-set on times_studied, which every write increases, and is
+// skipped when the session was already applied to the card.
+func (s *StatisticsService) applyCardAttempts(ctx context.Context, session models.StudySession, cardID string, attempts []models.CardAttempt) error {
+	masteryCollection := s.db.Collection("card_mastery")
+	filter := bson.M{
+		"card_id":    cardID,
+		"user_id":    session.UserID,
+		"cardset_id": session.CardSetID,
+	}
+
+	for range masteryUpdateRetries {
+		var mastery models.CardMastery
+		err := masteryCollection.FindOne(ctx, filter).Decode(&mastery)
+		exists := err == nil
+		if err == mongo.ErrNoDocuments {
+			mastery = models.CardMastery{
+				CardID:    cardID,
+				UserID:    session.UserID,
+				CardSetID: session.CardSetID,
+			}
+		} else if err != nil {
+			return err
+		}
+
+		for _, applied := range mastery.AppliedSessions {
+			if applied == session.ID {
+				return nil
+			}
+		}
+
+		timesStudied := mastery.TimesStudied
+		s.ApplyAttempts(&mastery, session.Mode, attempts)
+
+		// Only recent sessions can still be retried, so older ids are dropped
+		mastery.AppliedSessions = append(mastery.AppliedSessions, session.ID)
+		if len(mastery.AppliedSessions) > masteryAppliedSessionsCap {
+			mastery.AppliedSessions = mastery.AppliedSessions[len(mastery.AppliedSessions)-masteryAppliedSessionsCap:]
+		}
+
+		if !exists {
+			_, err = masteryCollection.InsertOne(ctx, mastery)
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return err
+		}
+
+		result, err := masteryCollection.UpdateOne(ctx, bson.M{
+			"card_id":       cardID,
+			"user_id":       session.UserID,
+			"cardset_id":    session.CardSetID,
+			"times_studied": timesStudied,
+		}, bson.M{"$set": mastery})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 1 {
+			return nil
+		}
+	}
+
+	return errors.New("card mastery kept changing concurrently")
+}
+
+// ApplyAttempts adds a session's attempts at a card to its counts and mode breakdown, and reviews
+// the card once. Learn sessions repeat a card until it is answered right, so the review only
+// passes when every attempt was correct; the confidence and time are those of the last attempt.
+func (s *StatisticsService) ApplyAttempts(mastery *models.CardMastery, mode models.StudyMode, attempts []models.CardAttempt) {
+	if len(attempts) == 0 {
+		return
+	}
+
+	recalled := true
+	for _, attempt := range attempts {
+		s.countAttempt(mastery, mode, attempt)
+		recalled = recalled && attempt.Correct
+	}
+
+	last := attempts[len(attempts)-1]
+	reviewedAt := last.AttemptedAt
+	if reviewedAt.IsZero() {
+		reviewedAt = time.Now()
+	}
+	quality := s.scheduler.GradeFromAttempt(recalled, last.ConfidenceLevel)
+	state := s.scheduler.Schedule(ReviewState{
+		EaseFactor:   mastery.EaseFactor,
+		IntervalDays: mastery.IntervalDays,
+		Repetitions:  mastery.Repetitions,
+		Lapses:       mastery.Lapses,
+		DueAt:        mastery.DueAt,
+	}, quality, reviewedAt)
+	mastery.EaseFactor = state.EaseFactor
+	mastery.IntervalDays = state.IntervalDays
+	mastery.Repetitions = state.Repetitions
+	mastery.Lapses = state.Lapses
+	mastery.DueAt = state.DueAt
+}
+
+// countAttempt adds an attempt to the counts and mode breakdown of a card
+func (s *StatisticsService) countAttempt(mastery *models.CardMastery, mode models.StudyMode, attempt models.CardAttempt) {
+	mastery.TimesStudied++
+	if attempt.Correct {
+		mastery.TimesCorrect++
+	} else {
+		mastery.TimesIncorrect++
+	}
+	mastery.MasteryLevel = float64(mastery.TimesCorrect) / float64(mastery.TimesStudied) * 100
+	mastery.LastStudied = attempt.AttemptedAt
+	mastery.LastCorrect = attempt.Correct
+	mastery.RecentResults = append(mastery.RecentResults, attempt.Correct)
+	if len(mastery.RecentResults) > MasteryRecentResults {
+		mastery.RecentResults = mastery.RecentResults[len(mastery.RecentResults)-MasteryRecentResults:]
+	}
+
+	// Per mode breakdown
+	if mastery.ModeStats == nil {
+		mastery.ModeStats = make(map[models.StudyMode]models.CardModeStats)
+	}
+	modeStats := mastery.ModeStats[mode]
+	modeStats.TimesStudied++
+	if attempt.Correct {
+		modeStats.TimesCorrect++
+	}
+	modeStats.LastStudied = attempt.AttemptedAt
+	mastery.ModeStats[mode] = modeStats
+}
+
+// RecentAccuracy is the percentage of the latest answers that were correct.
+// Records from before the latest answers were kept fall back to the lifetime ratio.
+func (s *StatisticsService) RecentAccuracy(mastery *models.CardMastery) float64 {
+	if mastery == nil || mastery.TimesStudied == 0 {
+		return 0
+	}
+	if len(mastery.RecentResults) == 0 {
+		return mastery.MasteryLevel
+	}
+
+	correct := 0
+	for _, result := range mastery.RecentResults {
+		if result {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(mastery.RecentResults)) * 100
+}
+
+// MasteryBucket classifies a card by its recent accuracy and its current run of successful
+// recalls, which the review schedule resets on every lapse. A nil record is a new card.
+func (s *StatisticsService) MasteryBucket(mastery *models.CardMastery) models.MasteryBucket {
+	if mastery == nil || mastery.TimesStudied == 0 {
+		return models.MasteryBucketNew
+	}
+
+	accuracy := s.RecentAccuracy(mastery)
+	switch {
+	case accuracy >= masteryBucketMasterAccuracy && mastery.Repetitions >= masteryBucketMasterRecalls:
+		return models.MasteryBucketMastered
+	case accuracy >= masteryBucketReviewAccuracy && mastery.Repetitions >= 1:
+		return models.MasteryBucketReviewing
+	default:
+		return models.MasteryBucketLearning
+	}
+}
+
+// RecalculateUser rebuilds a user's statistics from their sessions and card mastery.
+// The write only lands when no recalculation that started later landed first, so
+// concurrent jobs can't overwrite newer statistics with older ones.
+func (s *StatisticsService) RecalculateUser(ctx context.Context, userID primitive.ObjectID) error {
+	startedAt := time.Now()
+
+	var current models.UserStatistics
+	err := s.db.Collection("user_statistics").FindOne(ctx, bson.M{"user_id": userID}).Decode(&current)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	stats, err := s.ComputeUser(ctx, userID, current.StreakFreezes, current.LongestStreak)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Collection("user_statistics").UpdateOne(ctx,
+		bson.M{
+			"user_id": userID,
+			"$or": bson.A{
+				bson.M{"recalculated_at": bson.M{"$lt": startedAt}},
+				bson.M{"recalculated_at": bson.M{"$exists": false}},
+			},
+		},
+		bson.M{"$set": userStatisticsFields(stats, startedAt)},
+		options.Update().SetUpsert(true),
+	)
+	// The guard failed on existing statistics: a newer recalculation already landed
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// userStatisticsFields are the computed fields of the user statistics, leaving the streak freezes alone
+func userStatisticsFields(stats models.UserStatistics, recalculatedAt time.Time) bson.M {
+	return bson.M{
+		"total_study_time":    stats.TotalStudyTime,
+		"total_sessions":      stats.TotalSessions,
+		"total_attempts":      stats.TotalAttempts,
+		"overall_accuracy":    stats.OverallAccuracy,
+		"current_streak":      stats.CurrentStreak,
+		"longest_streak":      stats.LongestStreak,
+		"last_study_date":     stats.LastStudyDate,
+		"daily_stats":         stats.DailyStats,
+		"total_cards_studied": stats.TotalCardsStudied,
+		"cards_mastered":      stats.CardsMastered,
+		"cards_learning":      stats.CardsLearning,
+		"cards_new":           stats.CardsNew,
+		"recalculated_at":     recalculatedAt,
+		"updated_at":          time.Now(),
+	}
+}
+
+// ComputeUser derives a user's statistics from their sessions and card mastery, without saving them.
+// Totals are summed up in the database. Only the sessions of the daily stats window are loaded, and
+// earlier ones as long as the current streak goes back, so longest is the longest streak known so far.
+func (s *StatisticsService) ComputeUser(ctx context.Context, userID primitive.ObjectID, freezes []time.Time, longest int) (models.UserStatistics, error) {
+	stats := models.UserStatistics{UserID: userID, StreakFreezes: freezes}
+
+	cursor, err := s.db.Collection("study_sessions").Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"user_id": userID}},
+		bson.M{"$group": bson.M{
+			"_id":             nil,
+			"sessions":        bson.M{"$sum": 1},
+			"study_time":      bson.M{"$sum": "$duration"},
+			"attempts":        bson.M{"$sum": "$total_cards"},
+			"correct":         bson.M{"$sum": "$correct"},
+			"last_study_date": bson.M{"$max": "$start_time"},
+		}},
+	})
+	if err != nil {
+		return stats, err
+	}
+	var totals []struct {
+		Sessions      int       `bson:"sessions"`
+		StudyTime     int       `bson:"study_time"`
+		Attempts      int       `bson:"attempts"`
+		Correct       int       `bson:"correct"`
+		LastStudyDate time.Time `bson:"last_study_date"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return stats, err
+	}
+	if len(totals) > 0 {
+		stats.TotalSessions = totals[0].Sessions
+		stats.TotalStudyTime = totals[0].StudyTime
+		stats.TotalAttempts = totals[0].Attempts
+		stats.LastStudyDate = totals[0].LastStudyDate
+		if totals[0].Attempts > 0 {
+			stats.OverallAccuracy = float64(totals[0].Correct) / float64(totals[0].Attempts) * 100
+		}
+	}
+
+	// Days are local, so a day of margin covers the timezone offset; days from `from` on are complete
+	loc := s.UserLocation(ctx, userID)
+	today := s.streaks.Day(time.Now(), loc)
+	from := today.AddDate(0, 0, -(DailyStatsDays - 1))
+	sessions, err := s.sessionsBetween(ctx, userID, from.AddDate(0, 0, -1), time.Time{})
+	if err != nil {
+		return stats, err
+	}
+	stats.DailyStats = s.streaks.DailyStats(sessions, loc, today)
+
+	studyDays := s.streaks.StudyDays(sessions, loc)
+	for s.streaks.StreakReachesBack(studyDays, freezes, today, from) {
+		earlier, err := s.sessionsBetween(ctx, userID, from.AddDate(0, 0, -(DailyStatsDays+1)), from.AddDate(0, 0, -1))
+		if err != nil {
+			return stats, err
+		}
+		if len(earlier) == 0 {
+			break
+		}
+		studyDays = append(s.streaks.StudyDays(earlier, loc), studyDays...)
+		from = from.AddDate(0, 0, -DailyStatsDays)
+	}
+	stats.CurrentStreak, stats.LongestStreak = s.streaks.Streaks(studyDays, freezes, today)
+	stats.LongestStreak = max(stats.LongestStreak, longest)
+
+	masteryCollection := s.db.Collection("card_mastery")
+	studied, err := masteryCollection.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return stats, err
+	}
+	mastered, err := masteryCollection.CountDocuments(ctx, bson.M{
+		"user_id":       userID,
+		"mastery_level": bson.M{"$gte": MasteryLevelMastered},
+	})
+	if err != nil {
+		return stats, err
+	}
+	learning, err := masteryCollection.CountDocuments(ctx, bson.M{
+		"user_id":       userID,
+		"mastery_level": bson.M{"$gte": MasteryLevelLearning, "$lt": MasteryLevelMastered},
+	})
+	if err != nil {
+		return stats, err
+	}
+	newCards, err := masteryCollection.CountDocuments(ctx, bson.M{
+		"user_id":       userID,
+		"mastery_level": bson.M{"$lt": MasteryLevelLearning},
+	})
+	if err != nil {
+		return stats, err
+	}
+	stats.TotalCardsStudied = int(studied)
+	stats.CardsMastered = int(mastered)
+	stats.CardsLearning = int(learning)
+	stats.CardsNew = int(newCards)
+
+	return stats, nil
+}
+
+// sessionsBetween loads the summary of a user's sessions started from after until before,
+// or until now when before is zero
+func (s *StatisticsService) sessionsBetween(ctx context.Context, userID primitive.ObjectID, after, before time.Time) ([]models.StudySession, error) {
+	startTime := bson.M{"$gte": after}
+	if !before.IsZero() {
+		startTime["$lt"] = before
+	}
+
+	cursor, err := s.db.Collection("study_sessions").Find(ctx,
+		bson.M{"user_id": userID, "start_time": startTime},
+		options.Find().SetProjection(bson.M{"start_time": 1, "duration": 1, "total_cards": 1, "correct": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []models.StudySession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// userSessions loads all of a user's sessions in the order they were studied
+func (s *StatisticsService) userSessions(ctx context.Context, userID primitive.ObjectID) ([]models.StudySession, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "start_time", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.db.Collection("study_sessions").Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []models.StudySession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// summarizeSessions fills in the statistics derived from the sessions alone, given all of a user's sessions
+func (s *StatisticsService) summarizeSessions(ctx context.Context, userID primitive.ObjectID, sessions []models.StudySession, freezes []time.Time) models.UserStatistics {
+	stats := models.UserStatistics{UserID: userID, StreakFreezes: freezes}
+
+	totalCorrect := 0
+	for _, session := range sessions {
+		stats.TotalSessions++
+		stats.TotalStudyTime += session.Duration
+		stats.TotalAttempts += session.TotalCards
+		totalCorrect += session.Correct
+		if session.StartTime.After(stats.LastStudyDate) {
+			stats.LastStudyDate = session.StartTime
+		}
+	}
+	if stats.TotalAttempts > 0 {
+		stats.OverallAccuracy = float64(totalCorrect) / float64(stats.TotalAttempts) * 100
+	}
+
+	// Bucket the sessions into days in the user's timezone
+	loc := s.UserLocation(ctx, userID)
+	today := s.streaks.Day(time.Now(), loc)
+	stats.DailyStats = s.streaks.DailyStats(sessions, loc, today)
+	stats.CurrentStreak, stats.LongestStreak = s.streaks.Streaks(s.streaks.StudyDays(sessions, loc), freezes, today)
+
+	return stats
+}
+
+// UserLocation loads the timezone of a user, UTC when they haven't set one
+func (s *StatisticsService) UserLocation(ctx context.Context, userID primitive.ObjectID) *time.Location {
+	var user models.User
+	s.db.Collection("users").FindOne(ctx, bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"timezone": 1}),
+	).Decode(&user)
+	return s.streaks.Location(user.Timezone)
+}
+
+// StatisticsUserIDs lists every user, for rebuilding all statistics
+func (s *StatisticsService) StatisticsUserIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	cursor, err := s.db.Collection("users").Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"_id": 1}).SetSort(bson.D{{Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids, nil
+}
+
+// RebuildUser replays all sessions of a user in order to regenerate their card mastery and
+// statistics from scratch, and reports how they differ from the stored ones. A dry run only reports.
+// Card mastery written meanwhile fails the rebuild rather than being overwritten.
+func (s *StatisticsService) RebuildUser(ctx context.Context, userID primitive.ObjectID, dryRun bool) (models.StatisticsRebuildReport, error) {
+	report := models.StatisticsRebuildReport{UserID: userID, DryRun: dryRun, Changes: []models.StatisticsChange{}}
+	startedAt := time.Now()
+
+	sessions, err := s.userSessions(ctx, userID)
+	if err != nil {
+		return report, err
+	}
+	report.Sessions = len(sessions)
+
+	// Replay the attempts into fresh mastery records
+	rebuilt := make(map[masteryKey]*models.CardMastery)
+	for _, session := range sessions {
+		cardIDs, attemptsByCard := groupAttempts(session.Attempts)
+		for _, cardID := range cardIDs {
+			key := masteryKey{CardSetID: session.CardSetID, CardID: cardID}
+			mastery := rebuilt[key]
+			if mastery == nil {
+				mastery = &models.CardMastery{CardID: cardID, UserID: userID, CardSetID: session.CardSetID}
+				rebuilt[key] = mastery
+			}
+			s.ApplyAttempts(mastery, session.Mode, attemptsByCard[cardID])
+			mastery.AppliedSessions = append(mastery.AppliedSessions, session.ID)
+		}
+	}
+	for _, mastery := range rebuilt {
+		if len(mastery.AppliedSessions) > masteryAppliedSessionsCap {
+			mastery.AppliedSessions = mastery.AppliedSessions[len(mastery.AppliedSessions)-masteryAppliedSessionsCap:]
+		}
+	}
+
+	masteryCollection := s.db.Collection("card_mastery")
+	cursor, err := masteryCollection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return report, err
+	}
+	var stored []models.CardMastery
+	if err := cursor.All(ctx, &stored); err != nil {
+		return report, err
+	}
+	storedByKey := make(map[masteryKey]models.CardMastery, len(stored))
+	for _, mastery := range stored {
+		storedByKey[masteryKey{CardSetID: mastery.CardSetID, CardID: mastery.CardID}] = mastery
+	}
+
+	var current models.UserStatistics
+	err = s.db.Collection("user_statistics").FindOne(ctx, bson.M{"user_id": userID}).Decode(&current)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return report, err
+	}
+
+	stats := s.summarizeSessions(ctx, userID, sessions, current.StreakFreezes)
+	stats.TotalCardsStudied = len(rebuilt)
+	for _, mastery := range rebuilt {
+		switch {
+		case mastery.MasteryLevel >= MasteryLevelMastered:
+			stats.CardsMastered++
+		case mastery.MasteryLevel >= MasteryLevelLearning:
+			stats.CardsLearning++
+		default:
+			stats.CardsNew++
+		}
+	}
+	report.Changes = diffUserStatistics(current, stats)
+
+	changed := make([]masteryKey, 0)
+	for key, mastery := range rebuilt {
+		old, ok := storedByKey[key]
+		if !ok {
+			report.CardsAdded++
+			changed = append(changed, key)
+		} else if !sameMastery(old, *mastery) {
+			report.CardsChanged++
+			changed = append(changed, key)
+		}
+	}
+	removed := make([]masteryKey, 0)
+	for key := range storedByKey {
+		if rebuilt[key] == nil {
+			report.CardsRemoved++
+			removed = append(removed, key)
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	// Compare-and-set on times_studied against the stored records read above
+	for _, key := range changed {
+		mastery := rebuilt[key]
+		old, ok := storedByKey[key]
+		if !ok {
+			if _, err := masteryCollection.InsertOne(ctx, mastery); err != nil {
+				if mongo.IsDuplicateKeyError(err) {
+					return report, errors.New("card mastery changed during the rebuild")
+				}
+				return report, err
+			}
+			continue
+		}
+
+		result, err := masteryCollection.ReplaceOne(ctx, bson.M{
+			"card_id":       key.CardID,
+			"user_id":       userID,
+			"cardset_id":    key.CardSetID,
+			"times_studied": old.TimesStudied,
+		}, mastery)
+		if err != nil {
+			return report, err
+		}
+		if result.MatchedCount == 0 {
+			return report, errors.New("card mastery changed during the rebuild")
+		}
+	}
+	for _, key := range removed {
+		if _, err := masteryCollection.DeleteOne(ctx, bson.M{
+			"card_id":       key.CardID,
+			"user_id":       userID,
+			"cardset_id":    key.CardSetID,
+			"times_studied": storedByKey[key].TimesStudied,
+		}); err != nil {
+			return report, err
+		}
+	}
+
+	if _, err := s.db.Collection("user_statistics").UpdateOne(ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$set": userStatisticsFields(stats, startedAt)},
+		options.Update().SetUpsert(true),
+	); err != nil {
+		return report, err
+	}
+
+	// The replayed sessions are counted now; drop their pending jobs
+	sessionIDs := make([]primitive.ObjectID, len(sessions))
+	for i, session := range sessions {
+		sessionIDs[i] = session.ID
+	}
+	_, err = s.db.Collection("study_sessions").UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": sessionIDs}, "stats_job": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"stats_job": ""}},
+	)
+	return report, err
+}
+
+// masteryKey identifies the mastery record of a card of a user
+type masteryKey struct {
+	CardSetID primitive.ObjectID
+	CardID    string
+}
+
+// sameMastery compares the counts and schedule of two mastery records of the same card
+func sameMastery(a, b models.CardMastery) bool {
+	if a.TimesStudied != b.TimesStudied ||
+		a.TimesCorrect != b.TimesCorrect ||
+		a.TimesIncorrect != b.TimesIncorrect ||
+		a.MasteryLevel != b.MasteryLevel ||
+		!a.LastStudied.Equal(b.LastStudied) ||
+		a.LastCorrect != b.LastCorrect ||
+		a.EaseFactor != b.EaseFactor ||
+		a.IntervalDays != b.IntervalDays ||
+		a.Repetitions != b.Repetitions ||
+		a.Lapses != b.Lapses ||
+		!a.DueAt.Equal(b.DueAt) ||
+		!slices.Equal(a.RecentResults, b.RecentResults) ||
+		len(a.ModeStats) != len(b.ModeStats) {
+		return false
+	}
+
+	for mode, stats := range a.ModeStats {
+		other, ok := b.ModeStats[mode]
+		if !ok ||
+			stats.TimesStudied != other.TimesStudied ||
+			stats.TimesCorrect != other.TimesCorrect ||
+			!stats.LastStudied.Equal(other.LastStudied) {
+			return false
+		}
+	}
+	return true
+}
+
+// diffUserStatistics lists the computed fields that differ between stored and rebuilt statistics.
+// Daily stats are compared per day.
+func diffUserStatistics(old, rebuilt models.UserStatistics) []models.StatisticsChange {
+	changes := make([]models.StatisticsChange, 0)
+	add := func(field string, oldValue, newValue any, equal bool) {
+		if !equal {
+			changes = append(changes, models.StatisticsChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+
+	add("total_study_time", old.TotalStudyTime, rebuilt.TotalStudyTime, old.TotalStudyTime == rebuilt.TotalStudyTime)
+	add("total_sessions", old.TotalSessions, rebuilt.TotalSessions, old.TotalSessions == rebuilt.TotalSessions)
+	add("total_attempts", old.TotalAttempts, rebuilt.TotalAttempts, old.TotalAttempts == rebuilt.TotalAttempts)
+	add("total_cards_studied", old.TotalCardsStudied, rebuilt.TotalCardsStudied, old.TotalCardsStudied == rebuilt.TotalCardsStudied)
+	add("overall_accuracy", old.OverallAccuracy, rebuilt.OverallAccuracy, old.OverallAccuracy == rebuilt.OverallAccuracy)
+	add("current_streak", old.CurrentStreak, rebuilt.CurrentStreak, old.CurrentStreak == rebuilt.CurrentStreak)
+	add("longest_streak", old.LongestStreak, rebuilt.LongestStreak, old.LongestStreak == rebuilt.LongestStreak)
+	add("last_study_date", old.LastStudyDate, rebuilt.LastStudyDate, old.LastStudyDate.Equal(rebuilt.LastStudyDate))
+	add("cards_mastered", old.CardsMastered, rebuilt.CardsMastered, old.CardsMastered == rebuilt.CardsMastered)
+	add("cards_learning", old.CardsLearning, rebuilt.CardsLearning, old.CardsLearning == rebuilt.CardsLearning)
+	add("cards_new", old.CardsNew, rebuilt.CardsNew, old.CardsNew == rebuilt.CardsNew)
+
+	oldDays := make(map[time.Time]models.DailyStats, len(old.DailyStats))
+	for _, day := range old.DailyStats {
+		oldDays[day.Date.UTC()] = day
+	}
+	newDays := make(map[time.Time]models.DailyStats, len(rebuilt.DailyStats))
+	for _, day := range rebuilt.DailyStats {
+		newDays[day.Date.UTC()] = day
+	}
+	for _, day := range rebuilt.DailyStats {
+		field := "daily_stats[" + day.Date.Format(time.DateOnly) + "]"
+		if oldDay, ok := oldDays[day.Date.UTC()]; !ok {
+			add(field, nil, day, false)
+		} else {
+			oldDay.Date = day.Date
+			add(field, oldDay, day, oldDay == day)
+		}
+	}
+	for _, day := range old.DailyStats {
+		if _, ok := newDays[day.Date.UTC()]; !ok {
+			add("daily_stats["+day.Date.UTC().Format(time.DateOnly)+"]", day, nil, false)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"learn-backend/models"
+	"sort"
+	"time"
+)
+
+// DailyStatsDays is how many days of daily stats are kept on the user statistics
+const DailyStatsDays = 90
+
+// MaxStreakFreezesPerMonth is how many days a user can freeze in a calendar month
+const MaxStreakFreezesPerMonth = 2
+
+// StreakService buckets study sessions into calendar days in the user's timezone and
+// derives daily stats and streaks from them. Days are represented as midnight UTC of the
+// local calendar date, so they compare and serialise independently of the timezone.
+type StreakService struct{}
+
+func NewStreakService() *StreakService {
+	return &StreakService{}
+}
+
+// Location resolves an IANA timezone name, falling back to UTC when it is empty or unknown
+func (ss *StreakService) Location(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Day returns the calendar day t falls on in loc
+func (ss *StreakService) Day(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DailyStats rebuilds the daily stats of the last DailyStatsDays days before today
+// from the sessions, oldest day first
+func (ss *StreakService) DailyStats(sessions []models.StudySession, loc *time.Location, today time.Time) []models.DailyStats {
+	since := today.AddDate(0, 0, -(DailyStatsDays - 1))
+
+	byDay := make(map[time.Time]*models.DailyStats)
+	correct := make(map[time.Time]int)
+	for _, session := range sessions {
+		day := ss.Day(session.StartTime, loc)
+		if day.Before(since) || day.After(today) {
+			continue
+		}
+
+		stat := byDay[day]
+		if stat == nil {
+			stat = &models.DailyStats{Date: day}
+			byDay[day] = stat
+		}
+		stat.SessionsCount++
+		stat.CardsStudied += session.TotalCards
+		stat.TimeSpent += session.Duration
+		correct[day] += session.Correct
+	}
+
+	stats := make([]models.DailyStats, 0, len(byDay))
+	for day, stat := range byDay {
+		if stat.CardsStudied > 0 {
+			stat.Accuracy = float64(correct[day]) / float64(stat.CardsStudied) * 100
+		}
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Date.Before(stats[j].Date) })
+	return stats
+}
+
+// StudyDays returns the distinct days with at least one session, oldest first
+func (ss *StreakService) StudyDays(sessions []models.StudySession, loc *time.Location) []time.Time {
+	seen := make(map[time.Time]bool)
+	days := make([]time.Time, 0)
+	for _, session := range sessions {
+		day := ss.Day(session.StartTime, loc)
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// Streaks returns the current and longest run of study days. A run continues over
+// days without study when all of them are frozen; frozen days don't count towards it.
+// Today doesn't break the current streak before it is over.
+func (ss *StreakService) Streaks(studyDays, freezes []time.Time, today time.Time) (current, longest int) {
+	if len(studyDays) == 0 {
+		return 0, 0
+	}
+
+	frozen := frozenDays(freezes)
+	days := append([]time.Time(nil), studyDays...)
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	run := 0
+	for i, day := range days {
+		if i > 0 && day.Equal(days[i-1]) {
+			continue
+		}
+		if i == 0 || !ss.bridged(days[i-1], day, frozen) {
+			run = 0
+		}
+		run++
+		longest = max(longest, run)
+	}
+
+	// The last run is still going when nothing but frozen days (or today) followed it
+	if ss.bridged(days[len(days)-1], today, frozen) || days[len(days)-1].Equal(today) {
+		current = run
+	}
+	return current, longest
+}
+
+// CurrentStreak tells whether a stored streak ending on lastDay is still going today
+func (ss *StreakService) CurrentStreak(streak int, lastDay time.Time, freezes []time.Time, today time.Time) int {
+	if lastDay.Equal(today) {
+		return streak
+	}
+
+	if ss.bridged(lastDay, today, frozenDays(freezes)) {
+		return streak
+	}
+	return 0
+}
+
+// StreakReachesBack tells whether the current streak may have started before from,
+// when only the study days from from on are known
+func (ss *StreakService) StreakReachesBack(studyDays, freezes []time.Time, today, from time.Time) bool {
+	if len(studyDays) == 0 {
+		return false
+	}
+
+	frozen := frozenDays(freezes)
+	days := append([]time.Time(nil), studyDays...)
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	// Walk the current run back to its first day
+	start := days[len(days)-1]
+	if !start.Equal(today) && !ss.bridged(start, today, frozen) {
+		return false
+	}
+	for i := len(days) - 2; i >= 0; i-- {
+		if days[i].Equal(start) {
+			continue
+		}
+		if !ss.bridged(days[i], start, frozen) {
+			break
+		}
+		start = days[i]
+	}
+
+	return !start.After(from) || ss.bridged(from.AddDate(0, 0, -1), start, frozen)
+}
+
+// FreezesInMonth counts the frozen days in the calendar month of day
+func (ss *StreakService) FreezesInMonth(freezes []time.Time, day time.Time) int {
+	count := 0
+	for _, frozen := range freezes {
+		if frozen.Year() == day.Year() && frozen.Month() == day.Month() {
+			count++
+		}
+	}
+	return count
+}
+
+// frozenDays indexes the frozen days
+func frozenDays(freezes []time.Time) map[time.Time]bool {
+	frozen := make(map[time.Time]bool, len(freezes))
+	for _, day := range freezes {
+		frozen[day] = true
+	}
+	return frozen
+}
+
+// bridged reports whether every day strictly between from and to is frozen
+func (ss *StreakService) bridged(from, to time.Time, frozen map[time.Time]bool) bool {
+	if !to.After(from) {
+		return false
+	}
+	for day := from.AddDate(0, 0, 1); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !frozen[day] {
+			return false
+		}
+	}
+	return true
+}
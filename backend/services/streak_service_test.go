@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func days(s ...string) []time.Time {
+	parsed := make([]time.Time, len(s))
+	for i, d := range s {
+		parsed[i] = day(d)
+	}
+	return parsed
+}
+
+func TestStreaks(t *testing.T) {
+	today := day("2026-03-10")
+
+	tests := []struct {
+		name             string
+		studyDays        []time.Time
+		freezes          []time.Time
+		current, longest int
+	}{
+		{"no study days", nil, nil, 0, 0},
+		{"only today", days("2026-03-10"), nil, 1, 1},
+		{"run ending today", days("2026-03-08", "2026-03-09", "2026-03-10"), nil, 3, 3},
+		{"run ending yesterday isn't broken before today is over", days("2026-03-07", "2026-03-08", "2026-03-09"), nil, 3, 3},
+		{"gap before today breaks the run", days("2026-03-06", "2026-03-07", "2026-03-08"), nil, 0, 3},
+		{"gap keeps the longer earlier run", days("2026-03-01", "2026-03-02", "2026-03-03", "2026-03-09", "2026-03-10"), nil, 2, 3},
+		{"frozen day bridges a gap without counting", days("2026-03-08", "2026-03-10"), days("2026-03-09"), 2, 2},
+		{"frozen day keeps the run going until today", days("2026-03-07", "2026-03-08"), days("2026-03-09"), 2, 2},
+		{"partly frozen gap breaks the run", days("2026-03-06", "2026-03-09"), days("2026-03-07"), 1, 1},
+		{"duplicate days count once", days("2026-03-09", "2026-03-09", "2026-03-10", "2026-03-10"), nil, 2, 2},
+		{"unsorted days", days("2026-03-10", "2026-03-08", "2026-03-09"), nil, 3, 3},
+	}
+
+	ss := NewStreakService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, longest := ss.Streaks(tt.studyDays, tt.freezes, today)
+			if current != tt.current || longest != tt.longest {
+				t.Errorf("Streaks() = %d, %d, want %d, %d", current, longest, tt.current, tt.longest)
+			}
+		})
+	}
+}
+
+func TestBridged(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to time.Time
+		freezes  []time.Time
+		want     bool
+	}{
+		{"same day", day("2026-03-10"), day("2026-03-10"), nil, false},
+		{"backwards", day("2026-03-10"), day("2026-03-09"), nil, false},
+		{"next day", day("2026-03-09"), day("2026-03-10"), nil, true},
+		{"unfrozen gap", day("2026-03-08"), day("2026-03-10"), nil, false},
+		{"frozen gap", day("2026-03-07"), day("2026-03-10"), days("2026-03-08", "2026-03-09"), true},
+		{"partly frozen gap", day("2026-03-07"), day("2026-03-10"), days("2026-03-09"), false},
+		{"across a month", day("2026-02-28"), day("2026-03-02"), days("2026-03-01"), true},
+	}
+
+	ss := NewStreakService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ss.bridged(tt.from, tt.to, frozenDays(tt.freezes)); got != tt.want {
+				t.Errorf("bridged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreakReachesBack(t *testing.T) {
+	today := day("2026-03-10")
+	from := day("2026-03-05")
+
+	tests := []struct {
+		name      string
+		studyDays []time.Time
+		freezes   []time.Time
+		want      bool
+	}{
+		{"no study days", nil, nil, false},
+		{"no current streak", days("2026-03-05", "2026-03-06"), nil, false},
+		{"streak starts after from", days("2026-03-08", "2026-03-09", "2026-03-10"), nil, false},
+		{"streak starts on from", days("2026-03-05", "2026-03-06", "2026-03-07", "2026-03-08", "2026-03-09"), nil, true},
+		{"streak starts before from", days("2026-03-04", "2026-03-05", "2026-03-06", "2026-03-07", "2026-03-08", "2026-03-09", "2026-03-10"), nil, true},
+		{"only frozen days before the streak", days("2026-03-06", "2026-03-07", "2026-03-08", "2026-03-09"), days("2026-03-05"), true},
+		{"unfrozen day before the streak", days("2026-03-07", "2026-03-08", "2026-03-09"), days("2026-03-05"), false},
+	}
+
+	ss := NewStreakService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ss.StreakReachesBack(tt.studyDays, tt.freezes, today, from); got != tt.want {
+				t.Errorf("StreakReachesBack() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"learn-backend/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultTestQuestionCount = 20
+	testChoiceCount          = 4
+	testMatchGroupSize       = 5
+)
+
+// TestService is shared by concurrent requests; it draws from the
+// package-level math/rand/v2 source, which is safe for concurrent use
+type TestService struct {
+	grader *GradingService
+}
+
+func NewTestService() *TestService {
+	return &TestService{
+		grader: NewGradingService(),
+	}
+}
+
+// Generate builds the questions of a test from the cards of a set
+func (ts *TestService) Generate(cards []models.CardSetCard, count int, types []models.QuestionType, answerWith string) []models.TestQuestion {
+	if len(types) == 0 {
+		types = []models.QuestionType{
+			models.QuestionTypeMultipleChoice,
+			models.QuestionTypeTrueFalse,
+			models.QuestionTypeWritten,
+			models.QuestionTypeMatching,
+		}
+	}
+
+	picked := make([]models.CardSetCard, len(cards))
+	copy(picked, cards)
+	rand.Shuffle(len(picked), func(i, j int) { picked[i], picked[j] = picked[j], picked[i] })
+	if count > 0 && len(picked) > count {
+		picked = picked[:count]
+	}
+
+	// Spread the question types evenly over the picked cards
+	byType := map[models.QuestionType][]models.CardSetCard{}
+	for i, card := range picked {
+		questionType := types[i%len(types)]
+		byType[questionType] = append(byType[questionType], card)
+	}
+
+	// Matching needs at least two cards per group, a lone card is asked as written instead
+	matching := byType[models.QuestionTypeMatching]
+	if len(matching)%testMatchGroupSize == 1 {
+		byType[models.QuestionTypeWritten] = append(byType[models.QuestionTypeWritten], matching[len(matching)-1])
+		byType[models.QuestionTypeMatching] = matching[:len(matching)-1]
+	}
+
+	questions := []models.TestQuestion{}
+	for _, questionType := range []models.QuestionType{
+		models.QuestionTypeTrueFalse,
+		models.QuestionTypeMultipleChoice,
+		models.QuestionTypeWritten,
+	} {
+		for _, card := range byType[questionType] {
+			questions = append(questions, ts.buildQuestion(questionType, card, cards, ts.direction(answerWith)))
+		}
+	}
+
+	matching = byType[models.QuestionTypeMatching]
+	for start := 0; start < len(matching); start += testMatchGroupSize {
+		end := start + testMatchGroupSize
+		if end > len(matching) {
+			end = len(matching)
+		}
+		questions = append(questions, ts.buildMatching(matching[start:end], ts.direction(answerWith)))
+	}
+
+	return questions
+}
+
+// direction resolves the mixed direction to a concrete one per question
+func (ts *TestService) direction(answerWith string) string {
+	switch answerWith {
+	case models.AnswerWithTerm:
+		return models.AnswerWithTerm
+	case models.AnswerWithBoth:
+		if rand.IntN(2) == 0 {
+			return models.AnswerWithTerm
+		}
+	}
+	return models.AnswerWithDefinition
+}
+
+func (ts *TestService) buildQuestion(questionType models.QuestionType, card models.CardSetCard, cards []models.CardSetCard, answerWith string) models.TestQuestion {
+	prompt, answer := sides(card, answerWith)
+
+	question := models.TestQuestion{
+		ID:         uuid.New().String(),
+		CardID:     card.ID,
+		Type:       questionType,
+		AnswerWith: answerWith,
+		Prompt:     prompt,
+		Answer:     answer,
+	}
+
+	distractors := pickDistractors(card, cards, answerWith, testChoiceCount-1)
+
+	// Multiple choice falls back to true/false when the set is too small
+	if questionType == models.QuestionTypeMultipleChoice && len(distractors) == 0 {
+		questionType = models.QuestionTypeTrueFalse
+		question.Type = questionType
+	}
+
+	switch questionType {
+	case models.QuestionTypeTrueFalse:
+		question.Statement = answer
+		if len(distractors) > 0 && rand.IntN(2) == 0 {
+			question.Statement = distractors[0]
+		}
+		if question.Statement == answer {
+			question.Answer = "true"
+		} else {
+			question.Answer = "false"
+		}
+		question.Options = []string{"true", "false"}
+	case models.QuestionTypeMultipleChoice:
+		options := append([]string{answer}, distractors...)
+		rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+		question.Options = options
+	}
+
+	return question
+}
+
+func (ts *TestService) buildMatching(cards []models.CardSetCard, answerWith string) models.TestQuestion {
+	question := models.TestQuestion{
+		ID:         uuid.New().String(),
+		Type:       models.QuestionTypeMatching,
+		AnswerWith: answerWith,
+		Items:      make([]models.MatchItem, 0, len(cards)),
+		Options:    make([]string, 0, len(cards)),
+	}
+
+	for _, card := range cards {
+		prompt, answer := sides(card, answerWith)
+		question.Items = append(question.Items, models.MatchItem{
+			CardID: card.ID,
+			Prompt: prompt,
+			Answer: answer,
+		})
+		question.Options = append(question.Options, answer)
+	}
+	rand.Shuffle(len(question.Options), func(i, j int) {
+		question.Options[i], question.Options[j] = question.Options[j], question.Options[i]
+	})
+
+	return question
+}
+
+// Grade grades every question of a test. Unanswered questions count as incorrect.
+func (ts *TestService) Grade(questions []models.TestQuestion, answers []models.TestAnswer) []models.TestQuestionResult {
+	answersByID := map[string]models.TestAnswer{}
+	for _, answer := range answers {
+		answersByID[answer.QuestionID] = answer
+	}
+
+	results := []models.TestQuestionResult{}
+	for _, question := range questions {
+		answer := answersByID[question.ID]
+
+		if question.Type == models.QuestionTypeMatching {
+			for _, item := range question.Items {
+				userAnswer := answer.Matches[item.CardID]
+				results = append(results, models.TestQuestionResult{
+					QuestionID:    question.ID,
+					CardID:        item.CardID,
+					Correct:       strings.TrimSpace(userAnswer) == strings.TrimSpace(item.Answer),
+					UserAnswer:    userAnswer,
+					CorrectAnswer: item.Answer,
+				})
+			}
+			continue
+		}
+
+		correct := false
+		switch question.Type {
+		case models.QuestionTypeWritten:
+			correct = ts.grader.IsCorrect(answer.Answer, question.Answer)
+		default:
+			correct = strings.TrimSpace(answer.Answer) == question.Answer
+		}
+
+		results = append(results, models.TestQuestionResult{
+			QuestionID:    question.ID,
+			CardID:        question.CardID,
+			Correct:       correct,
+			UserAnswer:    answer.Answer,
+			CorrectAnswer: question.Answer,
+		})
+	}
+
+	return results
+}
+
+// ToAttempts converts graded results into card attempts, one per card
+func (ts *TestService) ToAttempts(results []models.TestQuestionResult, attemptedAt time.Time) []models.CardAttempt {
+	attempts := make([]models.CardAttempt, 0, len(results))
+	for _, result := range results {
+		attempts = append(attempts, models.CardAttempt{
+			CardID:      result.CardID,
+			Correct:     result.Correct,
+			UserAnswer:  result.UserAnswer,
+			AttemptedAt: attemptedAt,
+		})
+	}
+	return attempts
+}
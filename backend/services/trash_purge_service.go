@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TrashPurgeInterval is how often the purger looks for expired card sets
+const TrashPurgeInterval = time.Hour
+
+// cardSetDependents are the collections holding per card set data, keyed by cardset_id
+var cardSetDependents = []string{
+	"study_sessions",
+	"card_mastery",
+	"learn_sessions",
+	"tests",
+	"cardset_revisions",
+	"cardset_shares",
+	"cardset_ratings",
+	"cardset_reports",
+}
+
+// TrashPurgeService hard-deletes card sets that stayed in the trash longer than the retention period
+type TrashPurgeService struct {
+	db        *mongo.Database
+	stats     *StatisticsService
+	retention time.Duration
+}
+
+func NewTrashPurgeService(db *mongo.Database, retentionDays int) *TrashPurgeService {
+	return &TrashPurgeService{
+		db:        db,
+		stats:     NewStatisticsService(db),
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+// Start runs the purger in the background until ctx is cancelled
+func (s *TrashPurgeService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(TrashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.Purge(ctx); err != nil {
+				log.Printf("Failed to purge trashed card sets: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Purge deletes expired card sets together with their statistics and study data
+func (s *TrashPurgeService) Purge(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	cardSetsCollection := s.db.Collection("cardsets")
+	cutoff := time.Now().Add(-s.retention)
+	cursor, err := cardSetsCollection.Find(ctx,
+		bson.M{"deleted_at": bson.M{"$lt": cutoff}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return err
+	}
+
+	var expired []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &expired); err != nil {
+		return err
+	}
+
+	purged := 0
+	for _, cardSet := range expired {
+		// Claim the set first so it can't be restored while its dependents are deleted.
+		// A set claimed by an earlier run that failed matches again and is retried.
+		result, err := cardSetsCollection.UpdateOne(ctx,
+			bson.M{"_id": cardSet.ID, "deleted_at": bson.M{"$lt": cutoff}},
+			bson.M{"$set": bson.M{"purging_at": time.Now()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			// Restored meanwhile
+			continue
+		}
+
+		// The statistics of everyone who studied the set are recalculated without its sessions
+		userIDs, err := s.db.Collection("study_sessions").Distinct(ctx, "user_id", bson.M{"cardset_id": cardSet.ID})
+		if err != nil {
+			return err
+		}
+		// Queued before the sessions go too, so a failure below can't lose the users
+		if err := s.queueRecalculations(ctx, userIDs); err != nil {
+			return err
+		}
+
+		// Dependents first, so a failure leaves the claimed set to be retried next run
+		for _, name := range cardSetDependents {
+			if _, err := s.db.Collection(name).DeleteMany(ctx, bson.M{"cardset_id": cardSet.ID}); err != nil {
+				return err
+			}
+		}
+
+		// Again, in case the worker ran a recalculation before the sessions were deleted
+		if err := s.queueRecalculations(ctx, userIDs); err != nil {
+			return err
+		}
+
+		if _, err := cardSetsCollection.DeleteOne(ctx, bson.M{"_id": cardSet.ID}); err != nil {
+			return err
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d card sets from the trash", purged)
+	}
+
+	return nil
+}
+
+func (s *TrashPurgeService) queueRecalculations(ctx context.Context, userIDs []interface{}) error {
+	for _, id := range userIDs {
+		userID, ok := id.(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		if err := s.stats.QueueRecalculation(ctx, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ type Claims struct {
 	UserID   string `json:"user_id"`
 	Email    string `json:"email"`
 	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -21,11 +22,12 @@ type RefreshClaims struct {
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID, email, username, secret string, expiry time.Duration) (string, error) {
+func GenerateJWT(userID, email, username, role, secret string, expiry time.Duration) (string, error) {
 	claims := &Claims{
 		UserID:   userID,
 		Email:    email,
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -94,3 +96,24 @@ func GenerateSecureToken() (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
+
+const shortTokenAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateShortToken returns a random URL friendly token without look-alike characters
+func GenerateShortToken(length int) (string, error) {
+	// Reject bytes above the largest multiple of the alphabet size to avoid modulo bias
+	limit := 256 - 256%len(shortTokenAlphabet)
+	token := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(token) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(token) < length {
+				token = append(token, shortTokenAlphabet[int(b)%len(shortTokenAlphabet)])
+			}
+		}
+	}
+	return string(token), nil
+}
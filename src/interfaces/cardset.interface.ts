@@ -29,7 +29,185 @@ export interface ICardSet {
   user_id?: string;
   is_public?: boolean;
   download_count?: number;
+  rating_average?: number;
+  rating_count?: number;
+  hidden?: boolean;
+  forked_from?: IForkOrigin;
+  tags?: string[];
+  folder_id?: string;
+  collaborators?: ICollaborator[];
   phonetic_status?: PhoneticStatusType;
+  version?: number;
+}
+
+export interface IFolder {
+  id: string;
+  user_id: string;
+  parent_id?: string;
+  name: string;
+  cardset_count: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ITagCount {
+  tag: string;
+  count: number;
+}
+
+// folder_id 'root' lists the sets outside of all folders
+export interface ICardSetsFilter {
+  folder_id?: string;
+  tag?: string;
+}
+
+export type CollaboratorRole = 'viewer' | 'editor' | 'admin';
+
+// accepted_at is unset while the invitation is pending
+export interface ICollaborator {
+  user_id: string;
+  role: CollaboratorRole;
+  invited_by: string;
+  invited_at: string;
+  accepted_at?: string;
+}
+
+export interface IForkOrigin {
+  cardset_id: string;
+  revision: number;
+  synced_at: string;
+}
+
+export interface IUpstreamCardChange {
+  local: ICardSetCard;
+  upstream?: ICardSetCard;
+}
+
+export interface IUpstreamDiff {
+  upstream_id: string;
+  base_revision: number;
+  upstream_revision: number;
+  added: ICardSetCard[];
+  changed: IUpstreamCardChange[];
+  removed: ICardSetCard[];
+  conflicts: IUpstreamCardChange[];
+}
+
+export interface ISyncUpstreamResponse {
+  cardset: ICardSet;
+  diff: IUpstreamDiff;
+}
+
+export interface ICardSetSummary {
+  id: string;
+  user_id: string;
+  title: string;
+  description: string;
+  language?: string;
+  tags?: string[];
+  card_count: number;
+  download_count: number;
+  rating_average: number;
+  rating_count: number;
+  phonetic_status?: PhoneticStatusType;
+  created_at: string;
+  updated_at: string;
+}
+
+export type LibrarySort = 'popular' | 'newest' | 'trending' | 'rating';
+
+export interface IGlobalCardSetsParams {
+  q?: string;
+  language?: string;
+  tag?: string;
+  min_cards?: number;
+  sort?: LibrarySort;
+  cursor?: string;
+  limit?: number;
+}
+
+export interface ICardSetPage {
+  items: ICardSetSummary[];
+  next_cursor?: string;
+}
+
+export interface IPublicProfile {
+  id: string;
+  username: string;
+  full_name: string;
+  avatar: string;
+}
+
+export interface IPublicCardSetDetail extends Omit<ICardSetSummary, 'user_id'> {
+  owner: IPublicProfile;
+  cards: ICardSetCard[];
+}
+
+export interface ICollaboratorView extends ICollaborator {
+  user: IPublicProfile;
+}
+
+export interface ICardSetInvitation {
+  cardset_id: string;
+  title: string;
+  role: CollaboratorRole;
+  invited_by: IPublicProfile;
+  invited_at: string;
+}
+
+export interface ICardSetRating {
+  id: string;
+  cardset_id: string;
+  user_id: string;
+  stars: number;
+  comment?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ICardSetReview extends ICardSetRating {
+  user: IPublicProfile;
+}
+
+export interface ICardSetReviewPage {
+  items: ICardSetReview[];
+  next_cursor?: string;
+}
+
+export interface IRateCardSetResponse {
+  rating: ICardSetRating;
+  rating_average: number;
+  rating_count: number;
+}
+
+export type ReportReason = 'spam' | 'inappropriate' | 'copyright' | 'incorrect' | 'other';
+
+export interface ICardSetShare {
+  id: string;
+  token: string;
+  cardset_id: string;
+  has_password: boolean;
+  expires_at?: string;
+  max_uses?: number;
+  use_count: number;
+  created_at: string;
+}
+
+export interface ICreateShareParams {
+  expires_in_hours?: number;
+  max_uses?: number;
+  password?: string;
+}
+
+export interface ISharePreview {
+  token: string;
+  title: string;
+  description: string;
+  language: string;
+  card_count: number;
+  cards?: ICardSetCard[];
+  requires_password: boolean;
+  expires_at?: string;
 }
 
 export interface ICreateCardSetParams {
@@ -0,0 +1,71 @@
+import type { IPublicProfile } from './cardset.interface';
+
+export interface IClassStudent {
+  user_id: string;
+  joined_at: string;
+}
+
+// join_code and students are only sent to the teacher
+export interface IClass {
+  id: string;
+  teacher_id: string;
+  name: string;
+  description: string;
+  join_code?: string;
+  students?: IClassStudent[];
+  teacher: IPublicProfile;
+  student_count: number;
+  is_teacher: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+// student_cardset_id is the student's own copy once the assignment is started
+export interface IClassAssignment {
+  id: string;
+  class_id: string;
+  cardset_id: string;
+  assigned_by: string;
+  due_at?: string;
+  title: string;
+  card_count: number;
+  student_cardset_id?: string;
+  created_at: string;
+}
+
+export interface IAssignmentProgress {
+  assignment_id: string;
+  started: boolean;
+  sessions: number;
+  study_time: number;
+  accuracy: number;
+  cards_studied: number;
+  cards_mastered: number;
+  total_cards: number;
+  progress: number;
+  last_studied_at?: string;
+  overdue: boolean;
+}
+
+export interface IStudentReport {
+  student: IPublicProfile;
+  joined_at: string;
+  sessions: number;
+  study_time: number;
+  accuracy: number;
+  cards_mastered: number;
+  assignments: IAssignmentProgress[];
+}
+
+export interface IAssignmentReport extends IClassAssignment {
+  students_started: number;
+  students_completed: number;
+  average_progress: number;
+  average_accuracy: number;
+}
+
+export interface IClassReport {
+  class_id: string;
+  students: IStudentReport[];
+  assignments: IAssignmentReport[];
+}
@@ -1,6 +1,7 @@
 export * from './apiResponse';
 export * from './auth.interface';
 export * from './cardset.interface';
+export * from './class.interface';
 export * from './exam.interface';
 export * from './feedback.interface';
 export * from './leaderboard.interface';
@@ -1,4 +1,13 @@
-export type StudyMode = 'flashcard' | 'test' | 'write' | 'learn';
+import type { ICardSetCard } from './cardset.interface';
+
+export type StudyMode =
+  | 'flashcard'
+  | 'test'
+  | 'write'
+  | 'learn'
+  | 'listen'
+  | 'match'
+  | 'spell';
 
 export interface ICardAttempt {
   card_id: string;
@@ -43,6 +52,29 @@ export interface ICardMastery {
   mastery_level: number; // 0-100%
   last_studied: string;
   last_correct: boolean;
+  mode_stats?: Partial<Record<StudyMode, ICardModeStats>>;
+  recent_results?: boolean[]; // latest answers, oldest first
+}
+
+export interface ICardModeStats {
+  times_studied: number;
+  times_correct: number;
+  last_studied: string;
+}
+
+export type MasteryBucket = 'new' | 'learning' | 'reviewing' | 'mastered';
+
+export interface ICardMasteryDetail {
+  card: ICardSetCard;
+  bucket: MasteryBucket;
+  recent_accuracy: number; // percentage
+  mastery?: ICardMastery; // missing for cards never studied
+}
+
+export interface ICardSetMastery {
+  cardset_id: string;
+  buckets: Record<MasteryBucket, number>;
+  cards: ICardMasteryDetail[];
 }
 
 export interface IUserStatistics {
@@ -55,6 +87,7 @@ export interface IUserStatistics {
   overall_accuracy: number;
   current_streak: number;
   longest_streak: number;
+  streak_freezes?: string[];
   last_study_date: string;
   daily_stats: IDailyStats[];
   cards_mastered: number;
@@ -87,6 +120,33 @@ export interface ICardSetStatistics {
   };
   sessions: IStudySession[];
   card_mastery: ICardMastery[];
+  performance_by_mode: IPerformanceByMode[];
+}
+
+export type TimeseriesGranularity = 'day' | 'week' | 'month';
+
+export interface ITimeseriesParams {
+  from?: string; // YYYY-MM-DD in the user's timezone, defaults to 30 days before to
+  to?: string; // YYYY-MM-DD, defaults to today
+  granularity?: TimeseriesGranularity;
+  cardset_id?: string;
+  mode?: StudyMode;
+}
+
+export interface ITimeseriesBucket {
+  date: string; // first day of the bucket
+  sessions: number;
+  study_time: number; // seconds
+  cards_studied: number;
+  accuracy: number;
+}
+
+export interface ITimeseries {
+  from: string;
+  to: string;
+  granularity: TimeseriesGranularity;
+  timezone: string;
+  buckets: ITimeseriesBucket[];
 }
 
 export interface ICreateSessionRequest {
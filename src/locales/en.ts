@@ -249,6 +249,7 @@ export default {
     importJSON: 'Import JSON',
     share: 'Share',
     shareLink: 'Share Link',
+    shareLinkPassword: 'This share link is password protected',
     copyLink: 'Copy Link',
     linkCopied: 'Link Copied',
     importFromFile: 'Import from File',
@@ -308,6 +309,25 @@ export default {
     cards: 'cards',
     downloads: 'downloads',
     noDescription: 'No description',
+    loadMore: 'Load more',
+    rate: 'Rate this set:',
+    report: {
+      placeholder: 'Report a problem',
+      button: 'Report',
+      reasons: {
+        spam: 'Spam',
+        inappropriate: 'Inappropriate content',
+        copyright: 'Copyright violation',
+        incorrect: 'Incorrect content',
+        other: 'Other',
+      },
+    },
+    sort: {
+      popular: 'Most popular',
+      newest: 'Newest',
+      trending: 'Trending',
+      rating: 'Top rated',
+    },
     empty: {
       title: 'No public card sets yet',
       description: 'Be the first to publish your card set!',
@@ -316,6 +336,10 @@ export default {
       loadError: 'Failed to load card library',
       importSuccess: 'Card set imported successfully',
       importError: 'Failed to import card set',
+      rateSuccess: 'Thanks for your rating',
+      rateError: 'Failed to rate card set',
+      reportSuccess: 'Thanks, the card set has been reported',
+      reportError: 'Failed to report card set',
     },
   },
   studyModes: {
@@ -247,6 +247,7 @@ export default {
     importJSON: 'Nhập JSON',
     share: 'Chia sẻ',
     shareLink: 'Chia sẻ link',
+    shareLinkPassword: 'Link chia sẻ này được bảo vệ bằng mật khẩu',
     copyLink: 'Sao chép link',
     linkCopied: 'Đã sao chép link',
     importFromFile: 'Nhập từ file',
@@ -306,6 +307,25 @@ export default {
     cards: 'thẻ',
     downloads: 'lượt tải',
     noDescription: 'Không có mô tả',
+    loadMore: 'Tải thêm',
+    rate: 'Đánh giá bộ thẻ:',
+    report: {
+      placeholder: 'Báo cáo vấn đề',
+      button: 'Báo cáo',
+      reasons: {
+        spam: 'Spam',
+        inappropriate: 'Nội dung không phù hợp',
+        copyright: 'Vi phạm bản quyền',
+        incorrect: 'Nội dung sai',
+        other: 'Khác',
+      },
+    },
+    sort: {
+      popular: 'Phổ biến nhất',
+      newest: 'Mới nhất',
+      trending: 'Đang thịnh hành',
+      rating: 'Đánh giá cao nhất',
+    },
     empty: {
       title: 'Chưa có bộ thẻ công khai nào',
       description: 'Hãy là người đầu tiên công khai bộ thẻ của bạn!',
@@ -314,6 +334,10 @@ export default {
       loadError: 'Không thể tải thư viện bộ thẻ',
       importSuccess: 'Đã nhập bộ thẻ thành công',
       importError: 'Không thể nhập bộ thẻ',
+      rateSuccess: 'Cảm ơn bạn đã đánh giá',
+      rateError: 'Không thể đánh giá bộ thẻ',
+      reportSuccess: 'Cảm ơn, bộ thẻ đã được báo cáo',
+      reportError: 'Không thể báo cáo bộ thẻ',
     },
   },
   studyModes: {
@@ -1,5 +1,8 @@
 import apiService from './api.service';
 
+// Users without a role are students
+export type UserRole = 'student' | 'teacher';
+
 export interface IUser {
   id: string;
   username: string;
@@ -8,6 +11,8 @@ export interface IUser {
   avatar?: string;
   date_of_birth?: string;
   preferred_voice_id?: string;
+  role?: UserRole;
+  timezone?: string;
   created_at: string;
   updated_at: string;
 }
@@ -22,6 +27,7 @@ export interface IRegisterRequest {
   email: string;
   password: string;
   full_name: string;
+  role?: UserRole;
 }
 
 export interface ILoginResponse {
@@ -56,7 +62,7 @@ class AuthService {
     return await apiService.get<IUser>('/profile');
   }
 
-  async updateProfile(data: { full_name?: string; avatar?: string; date_of_birth?: string; preferred_voice_id?: string }): Promise<IUser> {
+  async updateProfile(data: { full_name?: string; avatar?: string; date_of_birth?: string; preferred_voice_id?: string; role?: UserRole; timezone?: string }): Promise<IUser> {
     return await apiService.put<IUser>('/profile', data);
   }
 
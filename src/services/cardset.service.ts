@@ -1,11 +1,33 @@
 import apiService from './api.service';
-import type { ICardSet, ICardSetCard } from '~/interfaces';
+import type {
+  ICardSet,
+  ICardSetCard,
+  ICardSetPage,
+  IGlobalCardSetsParams,
+  IPublicCardSetDetail,
+  ICardSetReviewPage,
+  IRateCardSetResponse,
+  ReportReason,
+  IUpstreamDiff,
+  ISyncUpstreamResponse,
+  ICardSetsFilter,
+  IFolder,
+  ITagCount,
+  CollaboratorRole,
+  ICollaboratorView,
+  ICardSetInvitation,
+  ICardSetShare,
+  ICreateShareParams,
+  ISharePreview,
+} from '~/interfaces';
 
 export interface ICreateCardSetRequest {
   title: string;
   description: string;
   language?: string;
   cards: ICardSetCard[];
+  tags?: string[];
+  folder_id?: string;
 }
 
 export interface IUpdateCardSetRequest {
@@ -13,11 +35,45 @@ export interface IUpdateCardSetRequest {
   description?: string;
   language?: string;
   cards?: ICardSetCard[];
+  tags?: string[];
 }
 
+// Card set writes must send the version they are based on (optimistic concurrency)
+const ifMatch = (version?: number) => ({
+  headers: { 'If-Match': `"${version ?? 0}"` },
+});
+
 class CardSetService {
-  async getCardSets(): Promise<ICardSet[]> {
-    return await apiService.get<ICardSet[]>('/cardsets');
+  async getCardSets(filter: ICardSetsFilter = {}): Promise<ICardSet[]> {
+    return await apiService.get<ICardSet[]>('/cardsets', { params: filter });
+  }
+
+  async getTags(): Promise<ITagCount[]> {
+    return await apiService.get<ITagCount[]>('/cardsets/tags');
+  }
+
+  async getFolders(): Promise<IFolder[]> {
+    return await apiService.get<IFolder[]>('/folders');
+  }
+
+  async createFolder(name: string, parentId?: string): Promise<IFolder> {
+    return await apiService.post<IFolder>('/folders', { name, parent_id: parentId });
+  }
+
+  // parent_id '' moves the folder to the root
+  async updateFolder(id: string, data: { name?: string; parent_id?: string }): Promise<IFolder> {
+    return await apiService.put<IFolder>(`/folders/${id}`, data);
+  }
+
+  async deleteFolder(id: string): Promise<void> {
+    await apiService.delete(`/folders/${id}`);
+  }
+
+  async moveCardSets(cardSetIds: string[], folderId?: string): Promise<{ moved: number }> {
+    return await apiService.post<{ moved: number }>('/cardsets/move', {
+      cardset_ids: cardSetIds,
+      folder_id: folderId ?? '',
+    });
   }
 
   async getCardSet(id: string): Promise<ICardSet> {
@@ -28,29 +84,129 @@ class CardSetService {
     return await apiService.post<ICardSet>('/cardsets', data);
   }
 
-  async updateCardSet(id: string, data: IUpdateCardSetRequest): Promise<ICardSet> {
-    return await apiService.put<ICardSet>(`/cardsets/${id}`, data);
+  async updateCardSet(
+    id: string,
+    data: IUpdateCardSetRequest,
+    version?: number
+  ): Promise<ICardSet> {
+    return await apiService.put<ICardSet>(
+      `/cardsets/${id}`,
+      data,
+      ifMatch(version)
+    );
   }
 
-  async deleteCardSet(id: string): Promise<void> {
-    await apiService.delete(`/cardsets/${id}`);
+  async deleteCardSet(id: string, version?: number): Promise<void> {
+    await apiService.delete(`/cardsets/${id}`, ifMatch(version));
   }
 
-  async togglePublish(id: string): Promise<ICardSet> {
-    return await apiService.post<ICardSet>(`/cardsets/${id}/publish`);
+  async togglePublish(id: string, version?: number): Promise<ICardSet> {
+    return await apiService.post<ICardSet>(
+      `/cardsets/${id}/publish`,
+      undefined,
+      ifMatch(version)
+    );
   }
 
-  async getGlobalCardSets(): Promise<ICardSet[]> {
-    return await apiService.get<ICardSet[]>('/cardsets/global');
+  async getGlobalCardSets(params: IGlobalCardSetsParams = {}): Promise<ICardSetPage> {
+    return await apiService.get<ICardSetPage>('/cardsets/global', { params });
+  }
+
+  async getPublicCardSet(id: string, preview?: number): Promise<IPublicCardSetDetail> {
+    return await apiService.get<IPublicCardSetDetail>(`/cardsets/global/${id}`, {
+      params: { preview },
+    });
+  }
+
+  async rateCardSet(id: string, stars: number, comment?: string): Promise<IRateCardSetResponse> {
+    return await apiService.post<IRateCardSetResponse>(`/cardsets/${id}/ratings`, {
+      stars,
+      comment,
+    });
+  }
+
+  async getReviews(id: string, cursor?: string): Promise<ICardSetReviewPage> {
+    return await apiService.get<ICardSetReviewPage>(`/cardsets/${id}/reviews`, {
+      params: { cursor },
+    });
+  }
+
+  async reportCardSet(id: string, reason: ReportReason, details?: string): Promise<void> {
+    await apiService.post(`/cardsets/${id}/report`, { reason, details });
   }
 
   async importFromGlobal(id: string): Promise<ICardSet> {
     return await apiService.post<ICardSet>(`/cardsets/${id}/import`);
   }
 
+  async getUpstreamDiff(id: string): Promise<IUpstreamDiff> {
+    return await apiService.get<IUpstreamDiff>(`/cardsets/${id}/upstream-diff`);
+  }
+
+  async syncUpstream(id: string, version?: number): Promise<ISyncUpstreamResponse> {
+    return await apiService.post<ISyncUpstreamResponse>(
+      `/cardsets/${id}/sync-upstream`,
+      undefined,
+      ifMatch(version)
+    );
+  }
+
   async generatePhonetics(id: string): Promise<ICardSet> {
     return await apiService.post<ICardSet>(`/cardsets/${id}/generate-phonetics`);
   }
+
+  async getSharedCardSets(): Promise<ICardSet[]> {
+    return await apiService.get<ICardSet[]>('/cardsets/shared');
+  }
+
+  async getInvitations(): Promise<ICardSetInvitation[]> {
+    return await apiService.get<ICardSetInvitation[]>('/cardsets/invitations');
+  }
+
+  async getCollaborators(id: string): Promise<ICollaboratorView[]> {
+    return await apiService.get<ICollaboratorView[]>(`/cardsets/${id}/collaborators`);
+  }
+
+  // user is an email address or username
+  async inviteCollaborator(id: string, user: string, role: CollaboratorRole): Promise<ICollaboratorView> {
+    return await apiService.post<ICollaboratorView>(`/cardsets/${id}/collaborators`, { user, role });
+  }
+
+  async updateCollaborator(id: string, userId: string, role: CollaboratorRole): Promise<void> {
+    await apiService.put(`/cardsets/${id}/collaborators/${userId}`, { role });
+  }
+
+  // Removing yourself leaves the card set or declines the invitation
+  async removeCollaborator(id: string, userId: string): Promise<void> {
+    await apiService.delete(`/cardsets/${id}/collaborators/${userId}`);
+  }
+
+  async acceptInvitation(id: string): Promise<ICardSet> {
+    return await apiService.post<ICardSet>(`/cardsets/${id}/collaborators/accept`);
+  }
+
+  async createShare(id: string, params: ICreateShareParams = {}): Promise<ICardSetShare> {
+    return await apiService.post<ICardSetShare>(`/cardsets/${id}/shares`, params);
+  }
+
+  async getShares(id: string): Promise<ICardSetShare[]> {
+    return await apiService.get<ICardSetShare[]>(`/cardsets/${id}/shares`);
+  }
+
+  async revokeShare(id: string, shareId: string): Promise<void> {
+    await apiService.delete(`/cardsets/${id}/shares/${shareId}`);
+  }
+
+  async getSharePreview(token: string, password?: string): Promise<ISharePreview> {
+    return await apiService.get<ISharePreview>(
+      `/shares/${token}`,
+      password ? { headers: { 'X-Share-Password': password } } : undefined
+    );
+  }
+
+  async importShare(token: string, password?: string): Promise<ICardSet> {
+    return await apiService.post<ICardSet>(`/shares/${token}/import`, { password });
+  }
 }
 
 export default new CardSetService();
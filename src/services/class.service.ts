@@ -0,0 +1,73 @@
+import apiService from './api.service';
+import type { ICardSet, IClass, IClassAssignment, IClassReport } from '~/interfaces';
+
+class ClassService {
+  async getClasses(): Promise<IClass[]> {
+    return await apiService.get<IClass[]>('/classes');
+  }
+
+  async getClass(id: string): Promise<IClass> {
+    return await apiService.get<IClass>(`/classes/${id}`);
+  }
+
+  async createClass(name: string, description = ''): Promise<IClass> {
+    return await apiService.post<IClass>('/classes', { name, description });
+  }
+
+  async updateClass(id: string, data: { name?: string; description?: string }): Promise<IClass> {
+    return await apiService.put<IClass>(`/classes/${id}`, data);
+  }
+
+  async deleteClass(id: string): Promise<void> {
+    await apiService.delete(`/classes/${id}`);
+  }
+
+  async resetJoinCode(id: string): Promise<{ join_code: string }> {
+    return await apiService.post<{ join_code: string }>(`/classes/${id}/join-code`);
+  }
+
+  async joinClass(code: string): Promise<IClass> {
+    return await apiService.post<IClass>('/classes/join', { code });
+  }
+
+  async leaveClass(id: string): Promise<void> {
+    await apiService.post(`/classes/${id}/leave`);
+  }
+
+  async removeStudent(id: string, userId: string): Promise<void> {
+    await apiService.delete(`/classes/${id}/students/${userId}`);
+  }
+
+  async getAssignments(id: string): Promise<IClassAssignment[]> {
+    return await apiService.get<IClassAssignment[]>(`/classes/${id}/assignments`);
+  }
+
+  async createAssignment(id: string, cardSetId: string, dueAt?: string): Promise<IClassAssignment> {
+    return await apiService.post<IClassAssignment>(`/classes/${id}/assignments`, {
+      cardset_id: cardSetId,
+      due_at: dueAt,
+    });
+  }
+
+  // Passing no due date removes it
+  async updateAssignment(id: string, assignmentId: string, dueAt?: string): Promise<IClassAssignment> {
+    return await apiService.put<IClassAssignment>(`/classes/${id}/assignments/${assignmentId}`, {
+      due_at: dueAt ?? null,
+    });
+  }
+
+  async deleteAssignment(id: string, assignmentId: string): Promise<void> {
+    await apiService.delete(`/classes/${id}/assignments/${assignmentId}`);
+  }
+
+  // Copies the assigned card set into the student's card sets, or returns the existing copy
+  async startAssignment(id: string, assignmentId: string): Promise<ICardSet> {
+    return await apiService.post<ICardSet>(`/classes/${id}/assignments/${assignmentId}/start`);
+  }
+
+  async getReport(id: string): Promise<IClassReport> {
+    return await apiService.get<IClassReport>(`/classes/${id}/report`);
+  }
+}
+
+export default new ClassService();
@@ -1,11 +1,12 @@
 export { default as apiService } from './api.service';
 export { default as authService } from './auth.service';
 export { default as cardSetService } from './cardset.service';
+export { default as classService } from './class.service';
 export { default as imageService } from './image.service';
 export { ttsService } from './tts.service';
 
 // Re-export types
-export type { IUser, ILoginRequest, IRegisterRequest, ILoginResponse } from './auth.service';
+export type { IUser, UserRole, ILoginRequest, IRegisterRequest, ILoginResponse } from './auth.service';
 export type { ICreateCardSetRequest, IUpdateCardSetRequest } from './cardset.service';
 export type { IPixabayImage, IPixabayResponse } from './image.service';
 export type { Voice } from './tts.service';
@@ -2,16 +2,26 @@ import apiService from './api.service';
 import type {
   IStatisticsResponse,
   ICardSetStatistics,
+  ICardSetMastery,
+  ITimeseries,
+  ITimeseriesParams,
   IStudySession,
   ICreateSessionRequest,
 } from '~/interfaces/statistics.interface';
 
 class StatisticsService {
   /**
-   * Record a new study session
+   * Record a new study session.
+   * The idempotency key defaults to one derived from the session start, so
+   * recording the same session twice only counts it once.
    */
-  async recordSession(data: ICreateSessionRequest): Promise<IStudySession> {
-    return apiService.post<IStudySession>('/statistics/sessions', data);
+  async recordSession(
+    data: ICreateSessionRequest,
+    idempotencyKey = `${data.cardset_id}:${data.mode}:${data.start_time}`
+  ): Promise<IStudySession> {
+    return apiService.post<IStudySession>('/statistics/sessions', data, {
+      headers: { 'Idempotency-Key': idempotencyKey },
+    });
   }
 
   /**
@@ -21,6 +31,13 @@ class StatisticsService {
     return apiService.get<IStatisticsResponse>('/statistics');
   }
 
+  /**
+   * Get study time, cards studied, accuracy and sessions per day, week or month
+   */
+  async getTimeseries(params: ITimeseriesParams = {}): Promise<ITimeseries> {
+    return apiService.get<ITimeseries>('/statistics/timeseries', { params });
+  }
+
   /**
    * Get statistics for a specific card set
    */
@@ -29,6 +46,24 @@ class StatisticsService {
       `/statistics/cardsets/${cardSetId}`
     );
   }
+
+  /**
+   * Get the cards of a card set grouped into mastery buckets by recent performance
+   */
+  async getCardSetMastery(cardSetId: string): Promise<ICardSetMastery> {
+    return apiService.get<ICardSetMastery>(
+      `/statistics/cardsets/${cardSetId}/mastery`
+    );
+  }
+
+  /**
+   * Freeze a day (YYYY-MM-DD, today or later) so skipping it keeps the streak
+   */
+  async freezeStreak(date: string): Promise<{ streak_freezes: string[] }> {
+    return apiService.post<{ streak_freezes: string[] }>('/statistics/streak-freezes', {
+      date,
+    });
+  }
 }
 
 export default new StatisticsService();
@@ -64,6 +64,12 @@ export const useAuthStore = defineStore(
       error.value = null;
       try {
         user.value = await authService.getProfile();
+
+        // Study days and streaks are counted in the user's timezone
+        const timezone = Intl.DateTimeFormat().resolvedOptions().timeZone;
+        if (!user.value.timezone && timezone) {
+          user.value = await authService.updateProfile({ timezone });
+        }
       } catch (err: any) {
         error.value = err.response?.data?.error || 'Failed to fetch profile';
         logout();
@@ -114,6 +120,7 @@ export const useAuthStore = defineStore(
       avatar?: string;
       date_of_birth?: string;
       preferred_voice_id?: string;
+      timezone?: string;
     }) => {
       loading.value = true;
       error.value = null;
@@ -1,6 +1,16 @@
 import { defineStore } from 'pinia';
 import { ref, computed } from 'vue';
-import type { ICardSet } from '~/interfaces/cardset.interface';
+import type {
+  ICardSet,
+  ICardSetSummary,
+  ICardSetInvitation,
+  ICardSetsFilter,
+  ICreateShareParams,
+  IGlobalCardSetsParams,
+  IPublicCardSetDetail,
+  ISharePreview,
+  ReportReason,
+} from '~/interfaces/cardset.interface';
 import cardSetService from '~/services/cardset.service';
 
 // Sample data for offline mode
@@ -44,11 +54,11 @@ export const useCardSetStore = defineStore('cardset', () => {
   };
 
   // Fetch all card sets from API
-  const fetchCardSets = async () => {
+  const fetchCardSets = async (filter: ICardSetsFilter = {}) => {
     loading.value = true;
     error.value = null;
     try {
-      cardSets.value = await cardSetService.getCardSets();
+      cardSets.value = await cardSetService.getCardSets(filter);
     } catch (err: any) {
       error.value = err.response?.data?.error || 'Failed to fetch card sets';
       console.error('Failed to fetch card sets:', err);
@@ -106,8 +116,12 @@ export const useCardSetStore = defineStore('cardset', () => {
     loading.value = true;
     error.value = null;
     try {
-      const updatedCardSet = await cardSetService.updateCardSet(id, updates);
       const index = cardSets.value.findIndex((cs) => cs.id === id);
+      const updatedCardSet = await cardSetService.updateCardSet(
+        id,
+        updates,
+        updates.version ?? cardSets.value[index]?.version
+      );
       if (index !== -1) {
         cardSets.value[index] = updatedCardSet;
       }
@@ -126,8 +140,8 @@ export const useCardSetStore = defineStore('cardset', () => {
     loading.value = true;
     error.value = null;
     try {
-      await cardSetService.deleteCardSet(id);
       const index = cardSets.value.findIndex((cs) => cs.id === id);
+      await cardSetService.deleteCardSet(id, cardSets.value[index]?.version);
       if (index !== -1) {
         cardSets.value.splice(index, 1);
       }
@@ -190,54 +204,59 @@ export const useCardSetStore = defineStore('cardset', () => {
     }
   };
 
-  // Generate shareable link for a cardset
-  const generateShareLink = (id: string): string => {
-    const cardSet = getCardSetById(id);
-    if (!cardSet) {
-      throw new Error('Card set not found');
-    }
+  // Create a server-side share link for a cardset
+  const generateShareLink = async (
+    id: string,
+    params: ICreateShareParams = {}
+  ): Promise<string> => {
+    const share = await cardSetService.createShare(id, params);
+    return `${window.location.origin}/import-shared?token=${share.token}`;
+  };
+
+  // Load a public cardset with its owner and first cards
+  const getPublicCardSet = async (id: string, preview?: number): Promise<IPublicCardSetDetail> => {
+    return await cardSetService.getPublicCardSet(id, preview);
+  };
 
-    // Create a simplified version without timestamps and user_id
-    const shareData = {
-      title: cardSet.title,
-      description: cardSet.description,
-      language: cardSet.language,
-      cards: cardSet.cards.map((card) => ({
-        terminology: card.terminology,
-        define: card.define,
-      })),
-    };
-
-    // Encode to base64
-    const jsonString = JSON.stringify(shareData);
-    const base64 = btoa(unescape(encodeURIComponent(jsonString)));
-    
-    // Generate URL with the base64 encoded data
-    const baseUrl = window.location.origin;
-    return `${baseUrl}/import-shared?data=${base64}`;
+  // Preview a shared cardset before importing it
+  const getSharePreview = async (token: string, password?: string): Promise<ISharePreview> => {
+    return await cardSetService.getSharePreview(token, password);
+  };
+
+  // Import a cardset from a share link token
+  const importFromShareLink = async (token: string, password?: string): Promise<ICardSet> => {
+    loading.value = true;
+    error.value = null;
+    try {
+      const newCardSet = await cardSetService.importShare(token, password);
+      cardSets.value.unshift(newCardSet);
+      return newCardSet;
+    } catch (err: any) {
+      const message = err.response?.data?.error || 'Failed to import from share link';
+      error.value = message;
+      console.error('Failed to import from share link:', err);
+      throw new Error(message);
+    } finally {
+      loading.value = false;
+    }
   };
 
-  // Import from shared link data
-  const importFromShareLink = async (base64Data: string): Promise<ICardSet> => {
+  // Import from a legacy share link that carries the cardset as base64 JSON
+  const importFromLegacyShareLink = async (base64Data: string): Promise<ICardSet> => {
     try {
-      // Decode from base64
       const jsonString = decodeURIComponent(escape(atob(base64Data)));
       const shareData = JSON.parse(jsonString);
 
-      // Validate the structure
       if (!shareData.title || !shareData.cards || !Array.isArray(shareData.cards)) {
         throw new Error('Invalid shared data format');
       }
 
-      // Create a new cardset with the shared data
-      const newCardSet = await addCardSet({
+      return await addCardSet({
         title: shareData.title,
         description: shareData.description || '',
         language: shareData.language,
         cards: shareData.cards,
       });
-
-      return newCardSet;
     } catch (err: any) {
       console.error('Failed to import from share link:', err);
       throw new Error('Invalid share link or data format');
@@ -249,8 +268,11 @@ export const useCardSetStore = defineStore('cardset', () => {
     loading.value = true;
     error.value = null;
     try {
-      const updatedCardSet = await cardSetService.togglePublish(id);
       const index = cardSets.value.findIndex((cs) => cs.id === id);
+      const updatedCardSet = await cardSetService.togglePublish(
+        id,
+        cardSets.value[index]?.version
+      );
       if (index !== -1) {
         cardSets.value[index] = updatedCardSet;
       }
@@ -264,13 +286,75 @@ export const useCardSetStore = defineStore('cardset', () => {
     }
   };
 
-  // Fetch global (public) card sets
-  const globalCardSets = ref<ICardSet[]>([]);
-  const fetchGlobalCardSets = async () => {
+  // Merge the source changes into a forked card set
+  const syncUpstream = async (id: string) => {
+    loading.value = true;
+    error.value = null;
+    try {
+      const index = cardSets.value.findIndex((cs) => cs.id === id);
+      const result = await cardSetService.syncUpstream(id, cardSets.value[index]?.version);
+      if (index !== -1) {
+        cardSets.value[index] = result.cardset;
+      }
+      return result;
+    } catch (err: any) {
+      error.value = err.response?.data?.error || 'Failed to sync card set';
+      console.error('Failed to sync upstream:', err);
+      throw err;
+    } finally {
+      loading.value = false;
+    }
+  };
+
+  // Card sets other users share with the current user, and pending invitations
+  const sharedCardSets = ref<ICardSet[]>([]);
+  const invitations = ref<ICardSetInvitation[]>([]);
+  const fetchSharedCardSets = async () => {
+    loading.value = true;
+    error.value = null;
+    try {
+      [sharedCardSets.value, invitations.value] = await Promise.all([
+        cardSetService.getSharedCardSets(),
+        cardSetService.getInvitations(),
+      ]);
+      return sharedCardSets.value;
+    } catch (err: any) {
+      error.value = err.response?.data?.error || 'Failed to fetch shared card sets';
+      console.error('Failed to fetch shared card sets:', err);
+      throw err;
+    } finally {
+      loading.value = false;
+    }
+  };
+
+  // Accept an invitation and move the set into the shared list
+  const acceptInvitation = async (id: string) => {
+    const cardSet = await cardSetService.acceptInvitation(id);
+    invitations.value = invitations.value.filter((inv) => inv.cardset_id !== id);
+    sharedCardSets.value.unshift(cardSet);
+    return cardSet;
+  };
+
+  // Decline an invitation, or leave a shared set
+  const leaveCardSet = async (id: string, userId: string) => {
+    await cardSetService.removeCollaborator(id, userId);
+    invitations.value = invitations.value.filter((inv) => inv.cardset_id !== id);
+    sharedCardSets.value = sharedCardSets.value.filter((cs) => cs.id !== id);
+  };
+
+  // Fetch global (public) card sets, one page at a time
+  const globalCardSets = ref<ICardSetSummary[]>([]);
+  const globalNextCursor = ref<string | undefined>(undefined);
+  const fetchGlobalCardSets = async (params: IGlobalCardSetsParams = {}, append = false) => {
     loading.value = true;
     error.value = null;
     try {
-      globalCardSets.value = await cardSetService.getGlobalCardSets();
+      const page = await cardSetService.getGlobalCardSets({
+        ...params,
+        cursor: append ? globalNextCursor.value : undefined,
+      });
+      globalCardSets.value = append ? [...globalCardSets.value, ...page.items] : page.items;
+      globalNextCursor.value = page.next_cursor;
       return globalCardSets.value;
     } catch (err: any) {
       error.value = err.response?.data?.error || 'Failed to fetch global card sets';
@@ -298,6 +382,22 @@ export const useCardSetStore = defineStore('cardset', () => {
     }
   };
 
+  // Rate a global card set and refresh its stored average
+  const rateCardSet = async (id: string, stars: number, comment?: string) => {
+    const result = await cardSetService.rateCardSet(id, stars, comment);
+    const summary = globalCardSets.value.find((cs) => cs.id === id);
+    if (summary) {
+      summary.rating_average = result.rating_average;
+      summary.rating_count = result.rating_count;
+    }
+    return result;
+  };
+
+  // Report a global card set for moderation
+  const reportCardSet = async (id: string, reason: ReportReason, details?: string) => {
+    await cardSetService.reportCardSet(id, reason, details);
+  };
+
   return {
     cardSets,
     loading,
@@ -317,8 +417,20 @@ export const useCardSetStore = defineStore('cardset', () => {
     generateShareLink,
     importFromShareLink,
     togglePublish,
+    syncUpstream,
+    sharedCardSets,
+    invitations,
+    fetchSharedCardSets,
+    acceptInvitation,
+    leaveCardSet,
     globalCardSets,
+    globalNextCursor,
     fetchGlobalCardSets,
     importFromGlobal,
+    getPublicCardSet,
+    rateCardSet,
+    reportCardSet,
+    getSharePreview,
+    importFromLegacyShareLink,
   };
 });
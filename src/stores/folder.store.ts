@@ -0,0 +1,89 @@
+import { defineStore } from 'pinia';
+import { ref, computed } from 'vue';
+import type { IFolder, ITagCount } from '~/interfaces/cardset.interface';
+import cardSetService from '~/services/cardset.service';
+
+export const useFolderStore = defineStore('folder', () => {
+  const folders = ref<IFolder[]>([]);
+  const tags = ref<ITagCount[]>([]);
+  const loading = ref(false);
+  const error = ref<string | null>(null);
+
+  // Folders directly under a parent, or at the root when parentId is omitted
+  const childrenOf = computed(
+    () => (parentId?: string) => folders.value.filter((f) => f.parent_id === parentId)
+  );
+
+  const fetchFolders = async () => {
+    loading.value = true;
+    error.value = null;
+    try {
+      folders.value = await cardSetService.getFolders();
+    } catch (err: any) {
+      error.value = err.response?.data?.error || 'Failed to fetch folders';
+      console.error('Failed to fetch folders:', err);
+    } finally {
+      loading.value = false;
+    }
+  };
+
+  const fetchTags = async () => {
+    try {
+      tags.value = await cardSetService.getTags();
+    } catch (err: any) {
+      console.error('Failed to fetch tags:', err);
+    }
+  };
+
+  const createFolder = async (name: string, parentId?: string) => {
+    const folder = await cardSetService.createFolder(name, parentId);
+    folders.value.push(folder);
+    return folder;
+  };
+
+  const renameFolder = async (id: string, name: string) => {
+    const folder = await cardSetService.updateFolder(id, { name });
+    replaceFolder(folder);
+    return folder;
+  };
+
+  const moveFolder = async (id: string, parentId?: string) => {
+    const folder = await cardSetService.updateFolder(id, { parent_id: parentId ?? '' });
+    replaceFolder(folder);
+    return folder;
+  };
+
+  // Subfolders and sets of a deleted folder move up to its parent
+  const deleteFolder = async (id: string) => {
+    await cardSetService.deleteFolder(id);
+    await fetchFolders();
+  };
+
+  const moveCardSets = async (cardSetIds: string[], folderId?: string) => {
+    const result = await cardSetService.moveCardSets(cardSetIds, folderId);
+    await fetchFolders();
+    return result;
+  };
+
+  const replaceFolder = (folder: IFolder) => {
+    const index = folders.value.findIndex((f) => f.id === folder.id);
+    if (index !== -1) {
+      folders.value[index] = { ...folder, cardset_count: folders.value[index].cardset_count };
+    }
+  };
+
+  return {
+    folders,
+    tags,
+    loading,
+    error,
+    childrenOf,
+    fetchFolders,
+    fetchTags,
+    createFolder,
+    renameFolder,
+    moveFolder,
+    deleteFolder,
+    moveCardSets,
+  };
+});
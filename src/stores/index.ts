@@ -1,5 +1,6 @@
 export * from './auth.store';
 export * from './cardset.store';
+export * from './folder.store';
 export * from './locale.store';
 export * from './ui.store';
 export * from './statistics.store';